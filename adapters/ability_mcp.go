@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/deep-project/agent/pkg/ability"
@@ -15,10 +16,13 @@ import (
 )
 
 type MCPAdapterOptions struct {
-	Name        string
-	Description string
-	Enable      bool
-	Timeout     time.Duration
+	Name            string
+	Description     string
+	Enable          bool
+	Timeout         time.Duration
+	RequireApproval bool     // 所有工具执行前都需要人工确认
+	ApprovalTools   []string // 执行前需要人工确认的工具名称
+	SerialTools     []string // 不能与其他工具并发执行的工具名称
 }
 
 type MCPAdapter struct {
@@ -75,13 +79,17 @@ func (m *MCPAdapter) Tools() (res []ability.Tool, _ error) {
 }
 
 func (m *MCPAdapter) CallTool(opt *ability.CallToolOptions) (*message.Message, error) {
+	return m.CallToolContext(context.Background(), opt)
+}
+
+func (m *MCPAdapter) CallToolContext(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
 	listDirRequest := mcp.CallToolRequest{Request: mcp.Request{Method: "tools/call"}}
 	listDirRequest.Params.Name = opt.Name
 	if opt.Args != nil {
 		listDirRequest.Params.Arguments = opt.Args.Map()
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.options.Timeout)
+	ctx, cancel := context.WithTimeout(ctx, m.options.Timeout)
 	defer cancel() // 确保退出前释放资源
 	result, err := m.client.CallTool(ctx, listDirRequest)
 	if err != nil {
@@ -94,6 +102,7 @@ func (m *MCPAdapter) CallTool(opt *ability.CallToolOptions) (*message.Message, e
 	return &message.Message{
 		Role:     message.RoleTool,
 		Contents: contents,
+		IsError:  result.IsError,
 	}, nil
 }
 
@@ -103,10 +112,12 @@ func (m *MCPAdapter) convertToAgentTool(mTool *mcp.Tool) (*ability.Tool, error)
 		return nil, err
 	}
 	return &ability.Tool{
-		Name:        mTool.Name,
-		Description: mTool.Description,
-		Enable:      true,
-		Parameters:  parameters,
+		Name:            mTool.Name,
+		Description:     mTool.Description,
+		Enable:          true,
+		Parameters:      parameters,
+		RequireApproval: m.options.RequireApproval || slices.Contains(m.options.ApprovalTools, mTool.Name),
+		Serial:          slices.Contains(m.options.SerialTools, mTool.Name),
 	}, nil
 }
 
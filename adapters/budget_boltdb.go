@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"encoding/json"
+
+	"github.com/deep-project/agent/pkg/budget"
+
+	"go.etcd.io/bbolt"
+)
+
+// BudgetBoltDBAdapter 使用bbolt保存限额使用量，可以与 MemoryBoltDBAdapter 共用一个数据库
+type BudgetBoltDBAdapter struct {
+	client *bbolt.DB
+}
+
+func NewBudgetBoltDBAdapter(client *bbolt.DB) *BudgetBoltDBAdapter {
+	return &BudgetBoltDBAdapter{client: client}
+}
+
+var budgetBucketName = []byte("budget")
+
+func (b *BudgetBoltDBAdapter) Get(key string) (res budget.Spend, err error) {
+	err = b.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(budgetBucketName)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(key)); v != nil {
+			return json.Unmarshal(v, &res)
+		}
+		return nil
+	})
+	return
+}
+
+func (b *BudgetBoltDBAdapter) Add(key string, spend budget.Spend) error {
+	return b.client.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(budgetBucketName)
+		if err != nil {
+			return err
+		}
+		var total budget.Spend
+		if v := bucket.Get([]byte(key)); v != nil {
+			if err = json.Unmarshal(v, &total); err != nil {
+				return err
+			}
+		}
+		total.Add(spend)
+		data, err := json.Marshal(&total)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(key), data)
+	})
+}
@@ -1,11 +1,19 @@
 package adapters
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
 	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
 
 	"go.etcd.io/bbolt"
 )
@@ -18,13 +26,62 @@ func NewMemoryBoltDBAdapter(client *bbolt.DB) *MemoryBoltDBAdapter {
 	return &MemoryBoltDBAdapter{client: client}
 }
 
-func (m *MemoryBoltDBAdapter) GetMeta(sessionID string) (ability.Meta, error) {
-	return ability.NewMeta(), nil
+// meta
+var metaBucketName = []byte("meta")
+
+func (m *MemoryBoltDBAdapter) GetMeta(sessionID string) (res ability.Meta, err error) {
+	res = ability.NewMeta()
+	err = m.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(metaBucketName)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(sessionID)); v != nil {
+			return json.Unmarshal(v, &res)
+		}
+		return nil
+	})
+	return
+}
+
+func (m *MemoryBoltDBAdapter) SetMeta(sessionID string, meta ability.Meta) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(metaBucketName)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(sessionID), data)
+	})
+}
+
+func (m *MemoryBoltDBAdapter) UpdateMeta(sessionID string, values ability.Meta) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(metaBucketName)
+		if err != nil {
+			return err
+		}
+		meta := ability.NewMeta()
+		if v := bucket.Get([]byte(sessionID)); v != nil {
+			if err = json.Unmarshal(v, &meta); err != nil {
+				return err
+			}
+		}
+		meta.Merge(values)
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(sessionID), data)
+	})
 }
 
 // message
 func (m *MemoryBoltDBAdapter) getMessageBucketName(sessionID string) []byte {
-	return []byte("messages-" + sessionID)
+	return []byte(messageBucketPrefix + sessionID)
 }
 
 func (m *MemoryBoltDBAdapter) HasMessageSession(sessionID string) (exists bool, err error) {
@@ -47,37 +104,298 @@ func (m *MemoryBoltDBAdapter) AddMessage(sessionID string, msg *message.Message)
 		if err != nil {
 			return err
 		}
-		id, err := bucket.NextSequence() // 自增 key 作为数组索引
-		if err != nil {
+		if err = m.putMessage(bucket, data); err != nil {
 			return err
 		}
-		return bucket.Put(fmt.Appendf(nil, "%d", id), data)
+		return m.touchSession(tx, sessionID, 1)
 	})
 }
 
+func (m *MemoryBoltDBAdapter) putMessage(bucket *bbolt.Bucket, data []byte) error {
+	id, err := bucket.NextSequence() // 自增 key 作为数组索引
+	if err != nil {
+		return err
+	}
+	return bucket.Put(fmt.Appendf(nil, "%d", id), data)
+}
+
 func (m *MemoryBoltDBAdapter) ListMessages(sessionID string, limit int) (res []message.Message, err error) {
 	err = m.client.View(func(tx *bbolt.Tx) error {
 		bucket := tx.Bucket(m.getMessageBucketName(sessionID))
 		if bucket == nil {
 			return fmt.Errorf("MemoryBoltDB bucket not found")
 		}
-		count := 0
-		cursor := bucket.Cursor()
-		for k, v := cursor.Last(); k != nil && count < limit; k, v = cursor.Prev() {
+		values := m.sortedMessages(bucket)
+		if limit > 0 && len(values) > limit {
+			values = values[len(values)-limit:] // 取最近的limit条消息
+		}
+		for _, v := range values {
 			var data message.Message
 			if err := json.Unmarshal(v, &data); err == nil {
 				res = append(res, data)
 			}
-			count++
 		}
-		m.reverseMessages(res) // 由于读取出来的数据是倒序的，所以这里要反转一下数组
 		return nil
 	})
 	return
 }
 
-func (m *MemoryBoltDBAdapter) reverseMessages(s []message.Message) {
-	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
-		s[i], s[j] = s[j], s[i]
+// sortedMessages 按写入顺序返回消息数据
+func (m *MemoryBoltDBAdapter) sortedMessages(bucket *bbolt.Bucket) [][]byte {
+	entries := m.sortedMessageEntries(bucket)
+	res := make([][]byte, len(entries))
+	for i, e := range entries {
+		res[i] = e.value
+	}
+	return res
+}
+
+type boltMessageEntry struct {
+	id    uint64
+	key   []byte
+	value []byte
+}
+
+// sortedMessageEntries 按写入顺序返回消息的key和数据
+// key是十进制的自增序号，按字节排序时"10"会排在"2"之前，所以需要按数值排序
+func (m *MemoryBoltDBAdapter) sortedMessageEntries(bucket *bbolt.Bucket) (entries []boltMessageEntry) {
+	bucket.ForEach(func(k, v []byte) error {
+		id, _ := strconv.ParseUint(string(k), 10, 64)
+		entries = append(entries, boltMessageEntry{id, k, v})
+		return nil
+	})
+	slices.SortFunc(entries, func(a, b boltMessageEntry) int { return cmp.Compare(a.id, b.id) })
+	return
+}
+
+func (m *MemoryBoltDBAdapter) TruncateMessages(sessionID string, n int) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(m.getMessageBucketName(sessionID))
+		if bucket == nil {
+			return memory.ErrSessionNotFound
+		}
+		entries := m.sortedMessageEntries(bucket)
+		if n < 0 {
+			n = 0
+		}
+		if n >= len(entries) {
+			return nil
+		}
+		for _, e := range entries[n:] {
+			if err := bucket.Delete(slices.Clone(e.key)); err != nil {
+				return err
+			}
+		}
+		s, err := m.getSession(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		s.MessageCount = n
+		s.UpdatedAt = time.Now()
+		return m.putSession(tx, s)
+	})
+}
+
+func (m *MemoryBoltDBAdapter) ReplaceMessage(sessionID string, msg *message.Message) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(m.getMessageBucketName(sessionID))
+		if bucket == nil || msg.ID == "" {
+			return memory.ErrMessageNotFound
+		}
+		for _, e := range m.sortedMessageEntries(bucket) {
+			var v message.Message
+			if err := json.Unmarshal(e.value, &v); err != nil || v.ID != msg.ID {
+				continue
+			}
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			return bucket.Put(slices.Clone(e.key), data)
+		}
+		return memory.ErrMessageNotFound
+	})
+}
+
+// session
+var sessionBucketName = []byte("sessions")
+
+const messageBucketPrefix = "messages-"
+
+// getSession 读取会话信息，早期版本没有保存会话信息，此时根据消息bucket生成
+func (m *MemoryBoltDBAdapter) getSession(tx *bbolt.Tx, sessionID string) (*memory.Session, error) {
+	if bucket := tx.Bucket(sessionBucketName); bucket != nil {
+		if v := bucket.Get([]byte(sessionID)); v != nil {
+			s := new(memory.Session)
+			return s, json.Unmarshal(v, s)
+		}
+	}
+	messages := tx.Bucket(m.getMessageBucketName(sessionID))
+	if messages == nil {
+		return nil, memory.ErrSessionNotFound
+	}
+	s := &memory.Session{ID: sessionID}
+	messages.ForEach(func(k, v []byte) error {
+		s.MessageCount++
+		return nil
+	})
+	return s, nil
+}
+
+func (m *MemoryBoltDBAdapter) putSession(tx *bbolt.Tx, s *memory.Session) error {
+	bucket, err := tx.CreateBucketIfNotExists(sessionBucketName)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return bucket.Put([]byte(s.ID), data)
+}
+
+// touchSession 新增消息后更新会话的消息数和时间
+func (m *MemoryBoltDBAdapter) touchSession(tx *bbolt.Tx, sessionID string, added int) error {
+	now := time.Now()
+	s, err := m.getSession(tx, sessionID)
+	if err != nil {
+		return err
+	}
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = now // 根据消息bucket生成的会话信息已经包含新增的消息
+	} else {
+		s.MessageCount += added
 	}
+	s.UpdatedAt = now
+	return m.putSession(tx, s)
+}
+
+func (m *MemoryBoltDBAdapter) ListSessions(offset, limit int) (res []memory.Session, total int, err error) {
+	var sessions []memory.Session
+	err = m.client.View(func(tx *bbolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bbolt.Bucket) error {
+			sessionID, ok := strings.CutPrefix(string(name), messageBucketPrefix)
+			if !ok {
+				return nil
+			}
+			s, err := m.getSession(tx, sessionID)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, *s)
+			return nil
+		})
+	})
+	if err != nil {
+		return
+	}
+	memory.SortSessions(sessions)
+	return memory.PageSessions(sessions, offset, limit), len(sessions), nil
+}
+
+func (m *MemoryBoltDBAdapter) GetSession(sessionID string) (res *memory.Session, err error) {
+	err = m.client.View(func(tx *bbolt.Tx) error {
+		res, err = m.getSession(tx, sessionID)
+		return err
+	})
+	return
+}
+
+func (m *MemoryBoltDBAdapter) DeleteSession(sessionID string) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(m.getMessageBucketName(sessionID)); err != nil && !errors.Is(err, bbolt.ErrBucketNotFound) {
+			return err
+		}
+		for _, name := range [][]byte{sessionBucketName, metaBucketName, usageBucketName} {
+			if bucket := tx.Bucket(name); bucket != nil {
+				if err := bucket.Delete([]byte(sessionID)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (m *MemoryBoltDBAdapter) RenameSession(sessionID, title string) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		s, err := m.getSession(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		s.Title = title
+		return m.putSession(tx, s)
+	})
+}
+
+func (m *MemoryBoltDBAdapter) ForkSession(sessionID, newSessionID string, n int) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		s, err := m.getSession(tx, sessionID)
+		if err != nil {
+			return err
+		}
+		if _, err = m.getSession(tx, newSessionID); err == nil {
+			return memory.ErrSessionExists
+		}
+		values := m.sortedMessages(tx.Bucket(m.getMessageBucketName(sessionID)))
+		if n > 0 && n < len(values) {
+			values = values[:n]
+		}
+		bucket, err := tx.CreateBucket(m.getMessageBucketName(newSessionID))
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			if err = m.putMessage(bucket, v); err != nil {
+				return err
+			}
+		}
+		if meta := tx.Bucket(metaBucketName); meta != nil {
+			if v := meta.Get([]byte(sessionID)); v != nil {
+				if err = meta.Put([]byte(newSessionID), slices.Clone(v)); err != nil {
+					return err
+				}
+			}
+		}
+		now := time.Now()
+		return m.putSession(tx, &memory.Session{ID: newSessionID, Title: s.Title, CreatedAt: now, UpdatedAt: now, MessageCount: len(values)})
+	})
+}
+
+// usage
+var usageBucketName = []byte("usage")
+
+func (m *MemoryBoltDBAdapter) AddUsage(sessionID string, u usage.Usage) error {
+	return m.client.Update(func(tx *bbolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(usageBucketName)
+		if err != nil {
+			return err
+		}
+		var total usage.Usage
+		if v := bucket.Get([]byte(sessionID)); v != nil {
+			if err = json.Unmarshal(v, &total); err != nil {
+				return err
+			}
+		}
+		total.Add(u)
+		data, err := json.Marshal(&total)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(sessionID), data)
+	})
+}
+
+func (m *MemoryBoltDBAdapter) GetUsage(sessionID string) (res usage.Usage, err error) {
+	err = m.client.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(usageBucketName)
+		if bucket == nil {
+			return nil
+		}
+		if v := bucket.Get([]byte(sessionID)); v != nil {
+			return json.Unmarshal(v, &res)
+		}
+		return nil
+	})
+	return
 }
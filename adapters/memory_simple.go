@@ -1,31 +1,68 @@
 package adapters
 
 import (
+	"maps"
 	"sync"
+	"time"
 
 	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
 	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
 )
 
 type MemorySimpleAdapter struct {
 	MaxSize int
 
-	store map[string][]message.Message
-	mu    sync.RWMutex
+	store    map[string][]message.Message
+	usage    map[string]usage.Usage
+	meta     map[string]ability.Meta
+	sessions map[string]*memory.Session
+	mu       sync.RWMutex
 }
 
 func NewMemorySimpleAdapter(maxSize int) *MemorySimpleAdapter {
 	return &MemorySimpleAdapter{
-		MaxSize: maxSize,
-		store:   make(map[string][]message.Message),
+		MaxSize:  maxSize,
+		store:    make(map[string][]message.Message),
+		usage:    make(map[string]usage.Usage),
+		meta:     make(map[string]ability.Meta),
+		sessions: make(map[string]*memory.Session),
 	}
 }
 
 func (m *MemorySimpleAdapter) GetMeta(sessionID string) (ability.Meta, error) {
-	return ability.NewMeta(), nil
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	meta, ok := m.meta[sessionID]
+	if !ok {
+		return ability.NewMeta(), nil
+	}
+	return maps.Clone(meta), nil
+}
+
+func (m *MemorySimpleAdapter) SetMeta(sessionID string, meta ability.Meta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.meta[sessionID] = maps.Clone(meta)
+	return nil
+}
+
+func (m *MemorySimpleAdapter) UpdateMeta(sessionID string, values ability.Meta) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	meta := maps.Clone(m.meta[sessionID])
+	if meta == nil {
+		meta = ability.NewMeta()
+	}
+	meta.Merge(values)
+	m.meta[sessionID] = meta
+	return nil
 }
 
 func (m *MemorySimpleAdapter) HasMessageSession(sessionID string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	_, exists := m.store[sessionID]
 	return exists, nil
 }
@@ -38,6 +75,85 @@ func (m *MemorySimpleAdapter) AddMessage(sessionID string, msg *message.Message)
 		messages = messages[1:] // 如果消息数量超过最大限制，则删除最早的一条消息
 	}
 	m.store[sessionID] = append(messages, *msg)
+	m.touchSession(sessionID)
+	return nil
+}
+
+// touchSession 更新会话的消息数和时间
+func (m *MemorySimpleAdapter) touchSession(sessionID string) {
+	now := time.Now()
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		s = &memory.Session{ID: sessionID, CreatedAt: now}
+		m.sessions[sessionID] = s
+	}
+	s.UpdatedAt = now
+	s.MessageCount = len(m.store[sessionID])
+}
+
+func (m *MemorySimpleAdapter) ListSessions(offset, limit int) ([]memory.Session, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := make([]memory.Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		sessions = append(sessions, *s)
+	}
+	memory.SortSessions(sessions)
+	return memory.PageSessions(sessions, offset, limit), len(sessions), nil
+}
+
+func (m *MemorySimpleAdapter) GetSession(sessionID string) (*memory.Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return nil, memory.ErrSessionNotFound
+	}
+	res := *s
+	return &res, nil
+}
+
+func (m *MemorySimpleAdapter) DeleteSession(sessionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.store, sessionID)
+	delete(m.sessions, sessionID)
+	delete(m.meta, sessionID)
+	delete(m.usage, sessionID)
+	return nil
+}
+
+func (m *MemorySimpleAdapter) RenameSession(sessionID, title string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return memory.ErrSessionNotFound
+	}
+	s.Title = title
+	return nil
+}
+
+func (m *MemorySimpleAdapter) ForkSession(sessionID, newSessionID string, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[sessionID]
+	if !ok {
+		return memory.ErrSessionNotFound
+	}
+	if _, ok = m.sessions[newSessionID]; ok {
+		return memory.ErrSessionExists
+	}
+	messages := m.store[sessionID]
+	if n > 0 && n < len(messages) {
+		messages = messages[:n]
+	}
+	m.store[newSessionID] = append([]message.Message{}, messages...)
+	now := time.Now()
+	m.sessions[newSessionID] = &memory.Session{ID: newSessionID, Title: s.Title, CreatedAt: now, UpdatedAt: now, MessageCount: len(messages)}
+	if meta, ok := m.meta[sessionID]; ok {
+		m.meta[newSessionID] = maps.Clone(meta)
+	}
 	return nil
 }
 
@@ -49,7 +165,51 @@ func (m *MemorySimpleAdapter) ListMessages(sessionID string, limit int) ([]messa
 		return []message.Message{}, nil
 	}
 	if limit > 0 && len(list) > limit {
-		return list[:limit], nil
+		list = list[len(list)-limit:] // 取最近的limit条消息
+	}
+	return append([]message.Message{}, list...), nil
+}
+
+func (m *MemorySimpleAdapter) AddUsage(sessionID string, u usage.Usage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	total := m.usage[sessionID]
+	total.Add(u)
+	m.usage[sessionID] = total
+	return nil
+}
+
+func (m *MemorySimpleAdapter) GetUsage(sessionID string) (usage.Usage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.usage[sessionID], nil
+}
+
+func (m *MemorySimpleAdapter) TruncateMessages(sessionID string, n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	messages, ok := m.store[sessionID]
+	if !ok {
+		return memory.ErrSessionNotFound
+	}
+	if n < 0 {
+		n = 0
+	}
+	if n < len(messages) {
+		m.store[sessionID] = messages[:n:n]
+		m.touchSession(sessionID)
+	}
+	return nil
+}
+
+func (m *MemorySimpleAdapter) ReplaceMessage(sessionID string, msg *message.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, v := range m.store[sessionID] {
+		if msg.ID != "" && v.ID == msg.ID {
+			m.store[sessionID][i] = *msg
+			return nil
+		}
 	}
-	return list, nil
+	return memory.ErrMessageNotFound
 }
@@ -4,37 +4,75 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/deep-project/agent/pkg/message"
 	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/usage"
 
 	"github.com/sashabaranov/go-openai"
 )
 
+var openAINameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
 type OpenAI struct {
-	client    *openai.Client
-	modelName string
+	client          *openai.Client
+	modelName       string
+	contextWindow   int            // 模型上下文窗口大小
+	maxOutputTokens int            // 为输出预留的token数
+	tokenizer       mind.Tokenizer // 为空则使用估算
 }
 
 func NewOpenAI(config openai.ClientConfig, modelName string) *OpenAI {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+	config.HTTPClient = &openAIHTTPDoer{doer: config.HTTPClient}
 	return &OpenAI{
 		client:    openai.NewClientWithConfig(config),
 		modelName: modelName,
 	}
 }
 
+// SetContextWindow 设置模型的上下文窗口大小和需要为输出预留的token数
+// 设置后，传给模型的历史消息会按照token数裁切
+func (o *OpenAI) SetContextWindow(contextWindow, maxOutputTokens int) *OpenAI {
+	o.contextWindow = contextWindow
+	o.maxOutputTokens = maxOutputTokens
+	return o
+}
+
+// SetTokenizer 设置与模型匹配的tokenizer
+func (o *OpenAI) SetTokenizer(tokenizer mind.Tokenizer) *OpenAI {
+	o.tokenizer = tokenizer
+	return o
+}
+
+func (o *OpenAI) ContextWindow() int {
+	return o.contextWindow
+}
+
+func (o *OpenAI) ReservedOutputTokens() int {
+	return o.maxOutputTokens
+}
+
+func (o *OpenAI) Tokenizer() mind.Tokenizer {
+	return o.tokenizer
+}
+
 func (o *OpenAI) Call(opt *mind.CallOptions) (*mind.CallResponse, error) {
-	req := openai.ChatCompletionRequest{
-		// 为什么不可以使用stream方式通信，经测试，stream方式同样会将tools的args也切割
-		// 如果硬要处理，就要将tool角色单独摘出来拼凑之后统一返回，处理复杂度过大。
-		//Stream: true,
-		Model:    o.modelName,
-		Tools:    o.convertToOpenAITools(opt.Tools),
-		Messages: o.convertToOpenAIMessage(opt.Messages),
-	}
-	resp, err := o.client.CreateChatCompletion(context.Background(), req)
+	return o.CallContext(context.Background(), opt)
+}
+
+func (o *OpenAI) CallContext(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+	ctx, header := withOpenAIResponseHeader(ctx)
+	resp, err := o.client.CreateChatCompletion(ctx, o.newRequest(opt))
 	if err != nil {
-		return nil, err
+		return nil, convertToStatusError(err, header)
 	}
 	if len(resp.Choices) == 0 {
 		return nil, errors.New("No response received")
@@ -42,9 +80,165 @@ func (o *OpenAI) Call(opt *mind.CallOptions) (*mind.CallResponse, error) {
 	choice := resp.Choices[0]
 	return &mind.CallResponse{
 		Message: *o.convertToAgentMessage(&choice.Message),
+		Model:   resp.Model,
+		Usage:   o.convertToAgentUsage(&resp.Usage),
 	}, nil
 }
 
+// CallStream 流式调用
+// stream方式会将tools的args切割成多个片段，需要按照tool call的index拼凑之后统一返回
+func (o *OpenAI) CallStream(ctx context.Context, opt *mind.CallOptions, onDelta func(*mind.StreamDelta) error) (*mind.CallResponse, error) {
+	req := o.newRequest(opt)
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	ctx, header := withOpenAIResponseHeader(ctx)
+	stream, err := o.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, convertToStatusError(err, header)
+	}
+	defer stream.Close()
+
+	acc := &openAIStreamAccumulator{}
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if chunk.Model != "" {
+			acc.model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			acc.usage = o.convertToAgentUsage(chunk.Usage)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		for _, delta := range acc.add(&chunk.Choices[0].Delta) {
+			if err = onDelta(delta); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !acc.received {
+		return nil, errors.New("No response received")
+	}
+	return &mind.CallResponse{
+		Message: *o.convertToAgentMessage(acc.message()),
+		Model:   acc.model,
+		Usage:   acc.usage,
+	}, nil
+}
+
+func (o *OpenAI) newRequest(opt *mind.CallOptions) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:    o.modelName,
+		Tools:    o.convertToOpenAITools(opt.Tools),
+		Messages: o.convertToOpenAIMessage(opt.Messages),
+	}
+	if f := opt.ResponseFormat; f != nil {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:        f.Name,
+				Description: f.Description,
+				Schema:      f.Schema,
+				Strict:      f.Strict,
+			},
+		}
+	}
+	return req
+}
+
+// openAIStreamAccumulator 拼接stream返回的片段
+type openAIStreamAccumulator struct {
+	model     string
+	usage     usage.Usage
+	received  bool
+	role      string
+	content   string
+	toolCalls []openai.ToolCall
+}
+
+// add 合并一个片段，返回需要输出的增量内容
+func (a *openAIStreamAccumulator) add(delta *openai.ChatCompletionStreamChoiceDelta) (res []*mind.StreamDelta) {
+	a.received = true
+	if delta.Role != "" {
+		a.role = delta.Role
+	}
+	if delta.Content != "" {
+		a.content += delta.Content
+		res = append(res, &mind.StreamDelta{Type: mind.StreamDeltaText, Text: delta.Content})
+	}
+	for _, t := range delta.ToolCalls {
+		index := a.toolCallIndex(&t)
+		if index == len(a.toolCalls) {
+			a.toolCalls = append(a.toolCalls, openai.ToolCall{ID: t.ID, Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: t.Function.Name}})
+			res = append(res, &mind.StreamDelta{Type: mind.StreamDeltaToolCallStart, ToolCallIndex: index, ToolCallID: t.ID, ToolID: t.Function.Name})
+		} else {
+			if t.ID != "" {
+				a.toolCalls[index].ID = t.ID
+			}
+			if t.Function.Name != "" && a.toolCalls[index].Function.Name == "" {
+				a.toolCalls[index].Function.Name = t.Function.Name
+			}
+		}
+		if t.Function.Arguments != "" {
+			a.toolCalls[index].Function.Arguments += t.Function.Arguments
+			call := a.toolCalls[index]
+			res = append(res, &mind.StreamDelta{Type: mind.StreamDeltaToolCallArguments, ToolCallIndex: index, ToolCallID: call.ID, ToolID: call.Function.Name, Arguments: t.Function.Arguments})
+		}
+	}
+	return
+}
+
+// toolCallIndex 获取片段所属的工具调用位置
+// 部分兼容openai的服务不返回index，此时根据id判断是否为新的工具调用
+func (a *openAIStreamAccumulator) toolCallIndex(t *openai.ToolCall) int {
+	if t.Index != nil {
+		if *t.Index < len(a.toolCalls) {
+			return *t.Index
+		}
+		return len(a.toolCalls)
+	}
+	if len(a.toolCalls) == 0 {
+		return 0
+	}
+	last := len(a.toolCalls) - 1
+	if t.ID != "" && t.ID != a.toolCalls[last].ID {
+		return len(a.toolCalls)
+	}
+	return last
+}
+
+func (a *openAIStreamAccumulator) message() *openai.ChatCompletionMessage {
+	role := a.role
+	if role == "" {
+		role = openai.ChatMessageRoleAssistant
+	}
+	return &openai.ChatCompletionMessage{Role: role, Content: a.content, ToolCalls: a.toolCalls}
+}
+
+func (o *OpenAI) convertToAgentUsage(u *openai.Usage) (res usage.Usage) {
+	if u == nil {
+		return
+	}
+	res = usage.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		res.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	if u.CompletionTokensDetails != nil {
+		res.ReasoningTokens = u.CompletionTokensDetails.ReasoningTokens
+	}
+	return
+}
+
 func (o *OpenAI) convertToOpenAITools(tools []mind.Tool) (res []openai.Tool) {
 	for _, t := range tools {
 		res = append(res, openai.Tool{
@@ -62,6 +256,7 @@ func (o *OpenAI) convertToOpenAITools(tools []mind.Tool) (res []openai.Tool) {
 func (o *OpenAI) convertToAgentMessage(msg *openai.ChatCompletionMessage) (res *message.Message) {
 	return &message.Message{
 		Role:      message.Role(msg.Role),
+		Name:      msg.Name,
 		Contents:  o.convertToAgentMessageContent(msg),
 		ToolCalls: o.convertToAgentToolCalls(&msg.ToolCalls),
 	}
@@ -101,16 +296,30 @@ func (o *OpenAI) convertToAgentMessageContent(msg *openai.ChatCompletionMessage)
 
 func (o *OpenAI) convertToOpenAIMessage(msg []message.Message) (res []openai.ChatCompletionMessage) {
 	for _, m := range msg {
-		res = append(res, openai.ChatCompletionMessage{
+		msg := openai.ChatCompletionMessage{
 			Role:         string(m.Role),
 			MultiContent: o.convertToOpenAIMessageContent(m.Contents),
 			ToolCalls:    o.convertToOpenAIToolCalls(m.ToolCalls),
 			ToolCallID:   m.ToolCallID,
-		})
+		}
+		// tool消息的name在openai中表示函数名称，不能用于区分参与者
+		if m.Role != message.RoleTool {
+			msg.Name = o.convertToOpenAIName(m.Name)
+		}
+		res = append(res, msg)
 	}
 	return res
 }
 
+// convertToOpenAIName openai的name只能包含字母、数字、下划线和中划线，最长64个字符
+func (o *OpenAI) convertToOpenAIName(name string) string {
+	name = openAINameRegexp.ReplaceAllString(name, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
 func (o *OpenAI) convertToOpenAIToolCalls(tools []message.ToolCall) (res []openai.ToolCall) {
 	for _, t := range tools {
 		res = append(res, openai.ToolCall{
@@ -143,3 +352,62 @@ func (o *OpenAI) convertToOpenAIMessageContent(content []message.Content) (res [
 	}
 	return res
 }
+
+type openAIResponseHeaderKey struct{}
+
+// withOpenAIResponseHeader 在context中放入一个容器，用于取回失败请求的响应头
+func withOpenAIResponseHeader(ctx context.Context) (context.Context, *http.Header) {
+	header := &http.Header{}
+	return context.WithValue(ctx, openAIResponseHeaderKey{}, header), header
+}
+
+// openAIHTTPDoer 包装http client，go-openai返回的错误中不包含响应头，需要在这里记录Retry-After
+type openAIHTTPDoer struct {
+	doer openai.HTTPDoer
+}
+
+func (d *openAIHTTPDoer) Do(req *http.Request) (*http.Response, error) {
+	resp, err := d.doer.Do(req)
+	if err != nil || resp.StatusCode < http.StatusBadRequest {
+		return resp, err
+	}
+	if header, ok := req.Context().Value(openAIResponseHeaderKey{}).(*http.Header); ok {
+		*header = resp.Header.Clone()
+	}
+	return resp, err
+}
+
+// convertToStatusError 将带状态码的错误包装为mind.StatusError，以便重试时判断
+func convertToStatusError(err error, header *http.Header) error {
+	var statusCode int
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	switch {
+	case errors.As(err, &apiErr):
+		statusCode = apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		statusCode = reqErr.HTTPStatusCode
+	}
+	if statusCode == 0 {
+		return err
+	}
+	return &mind.StatusError{
+		StatusCode: statusCode,
+		RetryAfter: parseRetryAfter(header.Get("Retry-After")),
+		Err:        err,
+	}
+}
+
+// parseRetryAfter 解析Retry-After，支持秒数和http日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return max(time.Duration(seconds*float64(time.Second)), 0)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
@@ -1,30 +1,74 @@
 package agent
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"sync"
 
 	"github.com/deep-project/agent/internal/helpers"
 	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/budget"
 	"github.com/deep-project/agent/pkg/memory"
 	"github.com/deep-project/agent/pkg/message"
 	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/usage"
 
 	"github.com/google/uuid"
 )
 
+const (
+	DefaultMaxSteps             = 20 // 默认的最大步数
+	DefaultMaxRepeatedToolCalls = 3  // 默认允许相同工具调用的次数
+)
+
+// ToolErrorPolicy 工具调用失败后的处理策略
+type ToolErrorPolicy int
+
+const (
+	ToolErrorFeedback ToolErrorPolicy = iota // 将错误信息作为工具调用结果反馈给思维
+	ToolErrorAbort                           // 中断交互，返回 ToolCallError
+)
+
+// StepLimitPolicy 达到步数限制后的处理策略
+type StepLimitPolicy int
+
+const (
+	StepLimitError       StepLimitPolicy = iota // 返回错误和已完成的部分结果
+	StepLimitFinalAnswer                        // 禁用工具，让思维给出最终回答
+)
+
 type Agent struct {
 	mind    *mind.Mind       // 思维
 	memory  *memory.Memory   // 记忆
 	ability *ability.Ability // 能力
-	mu      sync.Mutex
+
+	sessions sessionLocker // 会话锁，同一会话的交互依次执行，不同会话并行
+
+	maxSteps             int             // 每轮交互最大步数
+	maxRepeatedToolCalls int             // 每轮交互允许相同工具调用的次数
+	stepLimitPolicy      StepLimitPolicy // 达到限制后的处理策略
+	toolConcurrency      int             // 同时执行工具调用的数量
+	toolErrorPolicy      ToolErrorPolicy // 工具调用失败后的处理策略
+	instructions         string          // 指令（人设），每次调用思维时作为system消息
+	mindMiddlewares      []MindMiddleware
+	toolMiddlewares      []ToolMiddleware
+	errorHooks           []ErrorHook
+	compaction           *CompactionOptions // 对话压缩设置
+	pricing              usage.Pricing      // 模型价格表
+	budget               *budget.Budget     // 用量限额
+	budgetMu             sync.Mutex
+	planning             *PlanOptions // 计划执行模式设置，为空则不制定计划
+	mergeSameRole        bool         // 是否合并相邻的同角色消息
 }
 
 func New() *Agent {
 	return &Agent{
-		mind:    new(mind.Mind),
-		memory:  new(memory.Memory),
-		ability: new(ability.Ability),
+		mind:                 new(mind.Mind),
+		memory:               new(memory.Memory),
+		ability:              new(ability.Ability),
+		maxSteps:             DefaultMaxSteps,
+		maxRepeatedToolCalls: DefaultMaxRepeatedToolCalls,
 	}
 }
 
@@ -47,8 +91,6 @@ func (a *Agent) GrantAbility(handler ...ability.Handler) *Agent {
 
 // GrantAbilities 赋予智能体能力
 func (a *Agent) GrantAbilities(handlers []ability.Handler) *Agent {
-	a.mu.Lock()
-	defer a.mu.Unlock()
 	for _, handler := range handlers {
 		a.ability.Add(handler)
 	}
@@ -64,12 +106,40 @@ func (a *Agent) ResetAbilities(abilities []ability.Handler) *Agent {
 
 // ClearAbilities 清除所有能力
 func (a *Agent) ClearAbilities() *Agent {
-	a.mu.Lock()
-	defer a.mu.Unlock()
 	a.ability.Clear()
 	return a
 }
 
+// SetMaxSteps 设置每轮交互思维调用的最大步数，小于等于0则不限制
+func (a *Agent) SetMaxSteps(n int) *Agent {
+	a.maxSteps = n
+	return a
+}
+
+// SetMaxRepeatedToolCalls 设置每轮交互允许相同工具（相同参数）调用的次数，小于等于0则不检测
+func (a *Agent) SetMaxRepeatedToolCalls(n int) *Agent {
+	a.maxRepeatedToolCalls = n
+	return a
+}
+
+// SetStepLimitPolicy 设置达到步数限制后的处理策略
+func (a *Agent) SetStepLimitPolicy(policy StepLimitPolicy) *Agent {
+	a.stepLimitPolicy = policy
+	return a
+}
+
+// SetToolConcurrency 设置同一条消息中的工具调用同时执行的数量，小于等于0则不限制，为1则依次执行
+func (a *Agent) SetToolConcurrency(n int) *Agent {
+	a.toolConcurrency = n
+	return a
+}
+
+// SetToolErrorPolicy 设置工具调用失败后的处理策略
+func (a *Agent) SetToolErrorPolicy(policy ToolErrorPolicy) *Agent {
+	a.toolErrorPolicy = policy
+	return a
+}
+
 /////////
 
 // ListMessages 获取消息列表
@@ -89,7 +159,12 @@ func (a *Agent) HasMessageSession(sessionID string, messages []message.Message)
 
 // Talk 只返回文本聊天信息
 func (a *Agent) Talk(sessionID, text string) (sid string, res string, err error) {
-	output, err := a.Send(sessionID, text)
+	return a.TalkContext(context.Background(), sessionID, text)
+}
+
+// TalkContext 只返回文本聊天信息，可通过ctx取消
+func (a *Agent) TalkContext(ctx context.Context, sessionID, text string) (sid string, res string, err error) {
+	output, err := a.SendContext(ctx, sessionID, text)
 	if err != nil {
 		return
 	}
@@ -98,7 +173,12 @@ func (a *Agent) Talk(sessionID, text string) (sid string, res string, err error)
 
 // Send 返回完整消息结构体
 func (a *Agent) Send(sessionID, text string) (*InteractOutput, error) {
-	return a.Interact(&InteractInput{
+	return a.SendContext(context.Background(), sessionID, text)
+}
+
+// SendContext 返回完整消息结构体，可通过ctx取消
+func (a *Agent) SendContext(ctx context.Context, sessionID, text string) (*InteractOutput, error) {
+	return a.InteractContext(ctx, &InteractInput{
 		SessionID:     sessionID,
 		MessagesLimit: 50,
 		Messages: []message.Message{
@@ -108,93 +188,354 @@ func (a *Agent) Send(sessionID, text string) (*InteractOutput, error) {
 }
 
 // Interact 与agent交互
-func (a *Agent) Interact(input *InteractInput) (output *InteractOutput, err error) {
+func (a *Agent) Interact(input *InteractInput) (*InteractOutput, error) {
+	return a.InteractContext(context.Background(), input)
+}
+
+// InteractContext 与agent交互，ctx取消或超时会中断正在进行的思维调用和工具调用
+func (a *Agent) InteractContext(ctx context.Context, input *InteractInput) (output *InteractOutput, err error) {
 	if input == nil {
 		return nil, errors.New("interact input is empty")
 	}
-	a.mu.Lock()
-	defer a.mu.Unlock()
 	if input.SessionID == "" {
 		input.SessionID = uuid.New().String()
 	}
-	if err = a.AddMessages(input.SessionID, input.Messages); err != nil {
+	return a.interact(ctx, newTurn(input))
+}
+
+func (a *Agent) interact(ctx context.Context, t *turn) (output *InteractOutput, err error) {
+	unlock, err := a.sessions.Lock(ctx, t.input.SessionID)
+	if err != nil {
 		return
 	}
-	callResponse, err := a.call(input)
+	defer unlock()
+	cp, err := a.memory.Checkpoint(t.input.SessionID)
 	if err != nil {
-		return
+		return nil, a.handleError(ctx, err)
+	}
+	defer func() {
+		if err != nil {
+			err = a.rollback(cp, err)
+		}
+	}()
+	if err = a.mergeInputMeta(t); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	if err = a.memory.AddMessagesContext(ctx, t.input.SessionID, t.input.Messages); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	if err = a.compact(ctx, t); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	if output, err = a.run(ctx, t); err != nil {
+		return output, a.handleError(ctx, err)
+	}
+	return
+}
+
+// rollback 交互失败时删除本轮写入的消息，避免历史中留下没有回复的用户消息或没有结果的工具调用
+func (a *Agent) rollback(cp *memory.Checkpoint, err error) error {
+	if e := a.memory.Rollback(cp); e != nil {
+		return errors.Join(err, e)
 	}
-	return &InteractOutput{
-		SessionID: input.SessionID,
-		Message:   callResponse.Message,
-	}, nil
+	return err
 }
 
-func (a *Agent) call(input *InteractInput) (_ *mind.CallResponse, err error) {
-	messages, err := a.ListMessages(input.SessionID, input.MessagesLimit)
+// run 执行本轮交互，开启计划模式时先制定计划再逐步执行
+func (a *Agent) run(ctx context.Context, t *turn) (*InteractOutput, error) {
+	if a.planning != nil {
+		return a.callPlan(ctx, t)
+	}
+	return a.call(ctx, t)
+}
+
+// call 执行思维与工具调用的循环，直到思维不再需要调用工具或者达到步数限制
+func (a *Agent) call(ctx context.Context, t *turn) (output *InteractOutput, err error) {
+	output = t.output
+	maxSteps := a.maxSteps
+	if t.input.MaxSteps > 0 {
+		maxSteps = t.input.MaxSteps
+	}
+	repeated := make(map[string]int) // 相同工具调用的次数
+	start := output.Steps            // 计划执行模式下每个步骤单独计算步数
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if t.handoff != "" {
+			output.Status = InteractStatusHandoff
+			output.HandoffTo = t.handoff
+			return output, nil
+		}
+		if maxSteps > 0 && output.Steps-start >= maxSteps {
+			return a.stepLimit(ctx, t, ErrMaxStepsExceeded)
+		}
+		resp, err := a.callMind(ctx, t, true)
+		if err != nil {
+			return output, err
+		}
+		if len(resp.Message.ToolCalls) == 0 {
+			if err = a.validateResponse(t, &resp.Message); err != nil {
+				if err = a.retryResponse(t, err); err != nil {
+					return output, err
+				}
+				continue
+			}
+			output.Status = InteractStatusCompleted
+			return output, nil
+		}
+		if a.isRepeatedToolCalls(resp.Message.ToolCalls, repeated) {
+			if err = a.skipToolCalls(t, resp.Message.ToolCalls); err != nil {
+				return output, err
+			}
+			return a.stepLimit(ctx, t, ErrRepeatedToolCall)
+		}
+		// 需要人工确认的工具调用暂停执行，等待 Resume
+		pending, ready := a.splitApprovalToolCalls(t, resp.Message.ToolCalls)
+		if err = a.execToolCalls(ctx, t, ready); err != nil {
+			return output, err
+		}
+		if len(pending) > 0 {
+			output.Status = InteractStatusPendingApproval
+			output.PendingToolCalls = pending
+			return output, nil
+		}
+	}
+}
+
+// callMind 读取上下文消息并调用思维，返回的消息会存入记忆
+func (a *Agent) callMind(ctx context.Context, t *turn, withTools bool) (resp *mind.CallResponse, err error) {
+	if err = a.checkBudget(t.input.SessionID); err != nil {
+		return
+	}
+	messages, err := a.memory.ListMessagesContext(ctx, t.input.SessionID, t.input.MessagesLimit)
 	if err != nil {
 		return
 	}
+	messages = applySummary(messages)
 
-	// 如果经过裁切的消息以tool角色开头，则会导致AI执行错误
-	// 因为没有携带tool上一条消息，
-	// 所以需要过滤掉以tool角色开头的消息
-	messages = a.filterOutStartsWithToolRoleMessages(messages)
+	// 裁切或者中断后的历史可能拆开了工具调用与结果，思维会因此报错，需要先修复
+	messages = sanitizeMessages(messages, a.mergeSameRole)
 
 	if len(messages) == 0 {
 		return nil, errors.New("messages cannot be empty.")
 	}
-	tools, err := helpers.AbilityItemsToMindTools(a.ability.Items())
+	instructions, err := a.renderInstructions(t)
+	if err != nil {
+		return
+	}
+	if instructions != nil {
+		messages = append([]message.Message{*instructions}, messages...)
+	}
+	messages = append(messages, t.retryMessages...)
+	if t.prompt != "" {
+		messages = append(messages, message.Message{Role: message.RoleSystem, Contents: []message.Content{message.NewMessageWithContentText(t.prompt)}})
+	}
+	// 每一步使用能力列表的快照，工具ID与快照中的位置对应，执行工具时使用同一个快照
+	var tools []mind.Tool
+	if withTools {
+		t.items = a.ability.Items()
+		if tools, err = helpers.AbilityItemsToMindTools(t.items); err != nil {
+			return
+		}
+	}
+	messages = a.fitContextWindow(t, messages, tools)
+	meta, err := a.memory.GetMeta(t.input.SessionID)
 	if err != nil {
 		return
 	}
-	resp, err := a.mind.Call(&mind.CallOptions{Messages: messages, Tools: tools})
+	call := a.wrapMindCall(func(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+		if t.emit != nil {
+			return a.mind.CallStream(ctx, opt, t.emitDelta)
+		}
+		return a.mind.CallContext(ctx, opt)
+	})
+	resp, err = call(ctx, &mind.CallOptions{
+		Messages:       messages,
+		Tools:          tools,
+		ResponseFormat: t.responseFormat(),
+		Model:          t.input.Model,
+		Meta:           meta,
+	})
 	if err != nil {
 		return
 	}
 	if resp == nil {
 		return nil, errors.New("No response received")
 	}
-	if err = a.memory.AddMessage(input.SessionID, &resp.Message); err != nil {
+	if resp.Message.Model == "" {
+		resp.Message.Model = resp.Model
+	}
+	if err = a.recordUsage(t, resp); err != nil {
 		return
 	}
-	meta, err := a.memory.GetMeta(input.SessionID)
+	// 不符合格式的回复不存入记忆，由 retryResponse 作为临时上下文要求重新回复
+	if len(resp.Message.ToolCalls) > 0 || a.validateResponse(t, &resp.Message) == nil {
+		if err = a.memory.AddMessageContext(ctx, t.input.SessionID, &resp.Message); err != nil {
+			return
+		}
+		t.retryMessages = nil
+	}
+	t.output.Steps++
+	t.output.Message = resp.Message
+	t.addMessage(resp.Message)
+	for i := range resp.Message.ToolCalls {
+		t.send(StreamEvent{Type: StreamEventToolCallFinish, Index: i, ToolCall: &resp.Message.ToolCalls[i]})
+	}
+	t.send(StreamEvent{Type: StreamEventMessage, Message: &resp.Message})
+	return
+}
+
+// execToolCalls 执行工具调用，并按照调用顺序将结果存入记忆
+// 可以并发的工具调用会同时执行，标记为Serial的工具单独执行
+func (a *Agent) execToolCalls(ctx context.Context, t *turn, toolCalls []message.ToolCall) error {
+	meta, err := a.memory.GetMeta(t.input.SessionID)
 	if err != nil {
-		return
+		return err
 	}
-	if len(resp.Message.ToolCalls) > 0 {
-		for _, toolCall := range resp.Message.ToolCalls {
-			toolCallMsg, err := a.execToolCall(&toolCall, meta)
-			if err != nil {
-				continue
+	ctx = withParentCall(ctx, a, t)
+	results := make([]*message.Message, len(toolCalls))
+	errs := make([]error, len(toolCalls))
+	var batch []int
+	for i := range toolCalls {
+		if _, tool, err := helpers.FindAbilityTool(t.items, toolCalls[i].ToolID); err == nil && tool.Serial {
+			a.execToolCallBatch(ctx, t, batch, toolCalls, meta, results, errs)
+			a.execToolCallBatch(ctx, t, []int{i}, toolCalls, meta, results, errs)
+			batch = nil
+			continue
+		}
+		batch = append(batch, i)
+	}
+	a.execToolCallBatch(ctx, t, batch, toolCalls, meta, results, errs)
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	// 失败的工具调用也需要写入tool消息，否则思维会因为工具调用没有对应的结果而报错
+	var failed *ToolCallError
+	var budgetErr error
+	for i, msg := range results {
+		if errs[i] != nil {
+			msg = newToolErrorMessage(&toolCalls[i], errs[i])
+		}
+		if errors.Is(errs[i], ErrBudgetExceeded) && budgetErr == nil {
+			budgetErr = errs[i]
+		}
+		if msg.IsError && failed == nil {
+			failed = &ToolCallError{ToolCall: toolCalls[i], Err: errs[i]}
+			if failed.Err == nil {
+				failed.Err = ErrToolResultError
 			}
-			a.memory.AddMessage(input.SessionID, toolCallMsg)
 		}
-		return a.call(input)
+		if err = a.memory.AddMessageContext(ctx, t.input.SessionID, msg); err != nil {
+			return err
+		}
+		t.addToolResult(*msg)
+	}
+	if budgetErr != nil {
+		return budgetErr
+	}
+	if failed != nil && a.toolErrorPolicy == ToolErrorAbort {
+		return failed
+	}
+	return nil
+}
+
+// execToolCallBatch 并发执行一批工具调用，同时执行的数量受 toolConcurrency 限制
+func (a *Agent) execToolCallBatch(ctx context.Context, t *turn, batch []int, toolCalls []message.ToolCall, meta ability.Meta, results []*message.Message, errs []error) {
+	if len(batch) == 0 {
+		return
 	}
-	return resp, nil
+	concurrency := a.toolConcurrency
+	if concurrency <= 0 || concurrency > len(batch) {
+		concurrency = len(batch)
+	}
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, i := range batch {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			results[i], errs[i] = a.execToolCall(ctx, t.input.SessionID, t.items, &toolCalls[i], meta)
+		}(i)
+	}
+	wg.Wait()
 }
 
-func (a *Agent) filterOutStartsWithToolRoleMessages(msgs []message.Message) []message.Message {
-	var isFilter bool = true
-	var filtered []message.Message
-	for _, msg := range msgs {
-		if msg.Role != message.RoleTool {
-			isFilter = false
+// skipToolCalls 为不再执行的工具调用写入结果，保证每个工具调用都有对应的tool消息
+func (a *Agent) skipToolCalls(t *turn, toolCalls []message.ToolCall) error {
+	for _, toolCall := range toolCalls {
+		msg := newToolErrorMessage(&toolCall, errors.New("tool call skipped: repeated identical tool call"))
+		if err := a.memory.AddMessage(t.input.SessionID, msg); err != nil {
+			return err
 		}
-		if isFilter == false {
-			filtered = append(filtered, msg)
+		t.addToolResult(*msg)
+	}
+	return nil
+}
+
+// isRepeatedToolCalls 记录工具调用次数，相同工具和参数的调用超过限制时返回true
+func (a *Agent) isRepeatedToolCalls(toolCalls []message.ToolCall, repeated map[string]int) (res bool) {
+	if a.maxRepeatedToolCalls <= 0 {
+		return false
+	}
+	for _, toolCall := range toolCalls {
+		key := toolCall.ToolID + ":" + toolCall.Arguments.String()
+		repeated[key]++
+		if repeated[key] > a.maxRepeatedToolCalls {
+			res = true
 		}
 	}
-	return filtered
+	return
+}
+
+// stepLimit 达到步数限制后的处理
+// 根据策略返回错误或者禁用工具让思维给出最终回答
+func (a *Agent) stepLimit(ctx context.Context, t *turn, limitErr error) (*InteractOutput, error) {
+	if a.stepLimitPolicy != StepLimitFinalAnswer {
+		return t.output, limitErr
+	}
+	if _, err := a.callMind(ctx, t, false); err != nil {
+		return t.output, err
+	}
+	t.output.Status = InteractStatusCompleted
+	return t.output, nil
+}
+
+// newToolErrorMessage 生成工具调用失败的tool消息，内容为json格式的错误信息
+func newToolErrorMessage(toolCall *message.ToolCall, err error) *message.Message {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return &message.Message{
+		Role:       message.RoleTool,
+		Contents:   []message.Content{message.NewMessageWithContentText(string(data))},
+		ToolCallID: toolCall.ID,
+		IsError:    true,
+	}
 }
 
-func (a *Agent) execToolCall(toolCall *message.ToolCall, meta ability.Meta) (*message.Message, error) {
-	itemIndex, toolName, err := helpers.ParseMindToolID(toolCall.ToolID)
+func (a *Agent) execToolCall(ctx context.Context, sessionID string, items []ability.Item, toolCall *message.ToolCall, meta ability.Meta) (*message.Message, error) {
+	item, tool, err := helpers.FindAbilityTool(items, toolCall.ToolID)
 	if err != nil {
 		return nil, err
 	}
-	msg, err := a.ability.Call(itemIndex, toolName, &toolCall.Arguments, meta)
+	if err = a.reserveToolCallBudget(sessionID); err != nil {
+		return nil, err
+	}
+	call := a.wrapToolCall(item.CallTool)
+	msg, err := call(ctx, &ability.CallToolOptions{
+		Name:       tool.Name,
+		Args:       &toolCall.Arguments,
+		Meta:       meta,
+		SessionID:  sessionID,
+		ToolCallID: toolCall.ID,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -204,17 +545,38 @@ func (a *Agent) execToolCall(toolCall *message.ToolCall, meta ability.Meta) (*me
 	if msg.Role == "" {
 		msg.Role = message.RoleTool
 	}
+	if msg.UpdateMeta != nil {
+		if err = a.memory.UpdateMeta(sessionID, msg.UpdateMeta); err != nil {
+			return nil, err
+		}
+		msg.UpdateMeta = nil
+	}
 	msg.ToolCallID = toolCall.ID
 	return msg, nil
 }
 
 type InteractInput struct {
-	SessionID     string            `json:"session_id"`
-	Messages      []message.Message `json:"messages"`
-	MessagesLimit int               `json:"messages_limit"` // 限制对话上文消息数
+	SessionID          string               `json:"session_id"`
+	Messages           []message.Message    `json:"messages"`
+	MessagesLimit      int                  `json:"messages_limit"`       // 限制对话上文消息数
+	MaxSteps           int                  `json:"max_steps"`            // 限制思维调用的最大步数，为0则使用agent的设置
+	Instructions       string               `json:"instructions"`         // 本轮交互的指令，不为空则替代agent的指令
+	MaxContextTokens   int                  `json:"max_context_tokens"`   // 传给思维的上下文token数上限，为0则使用思维声明的上下文窗口
+	ResponseFormat     *mind.ResponseFormat `json:"response_format"`      // 要求最终回复符合的格式
+	MaxResponseRetries int                  `json:"max_response_retries"` // 回复不符合格式时重新要求回复的次数，为0则使用默认值，小于0则不重试
+	Model              string               `json:"model"`                // 指定使用的模型，思维为路由时按此选择
+	Meta               ability.Meta         `json:"meta"`                 // 合并到会话meta，如认证后的用户ID和租户，工具调用时可以获取
 }
 
 type InteractOutput struct {
-	SessionID string          `json:"session_id"`
-	Message   message.Message `json:"message"`
+	SessionID        string             `json:"session_id"`
+	Status           InteractStatus     `json:"status"`
+	Message          message.Message    `json:"message"`                      // 最终回复的消息
+	Messages         []message.Message  `json:"messages"`                     // 本轮交互产生的所有消息，包括工具调用结果
+	Steps            int                `json:"steps"`                        // 本轮交互调用思维的次数
+	PendingToolCalls []message.ToolCall `json:"pending_tool_calls,omitempty"` // 等待人工确认的工具调用
+	Usage            usage.Usage        `json:"usage"`                        // 本轮交互所有思维调用的累计用量
+	Agent            string             `json:"agent,omitempty"`              // 通过Team交互时，给出最终回复的agent名称
+	HandoffTo        string             `json:"handoff_to,omitempty"`         // 对话转交的目标agent名称
+	Plan             *Plan              `json:"plan,omitempty"`               // 计划执行模式下的计划和各步骤状态
 }
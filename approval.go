@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/deep-project/agent/internal/helpers"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// 查找待确认工具调用时读取的最近消息数
+const pendingMessagesLimit = 100
+
+type InteractStatus string
+
+const (
+	InteractStatusCompleted       InteractStatus = "completed"        // 交互完成
+	InteractStatusPendingApproval InteractStatus = "pending_approval" // 等待人工确认工具调用
+	InteractStatusHandoff         InteractStatus = "handoff"          // 对话已转交给其他agent
+)
+
+// Approval 人工确认结果
+type Approval struct {
+	ToolCallID string `json:"tool_call_id"`
+	Approved   bool   `json:"approved"`
+	Reason     string `json:"reason,omitempty"` // 拒绝原因，会反馈给思维
+}
+
+// Resume 确认工具调用后继续交互
+func (a *Agent) Resume(sessionID string, approvals []Approval) (*InteractOutput, error) {
+	return a.ResumeContext(context.Background(), &InteractInput{SessionID: sessionID, MessagesLimit: 50}, approvals)
+}
+
+// ResumeContext 确认工具调用后继续交互
+// 同意的工具调用会被执行，拒绝或未确认的工具调用会将拒绝原因反馈给思维
+// input中的Messages必须为空，其他设置与 InteractContext 一致
+func (a *Agent) ResumeContext(ctx context.Context, input *InteractInput, approvals []Approval) (output *InteractOutput, err error) {
+	if input == nil || input.SessionID == "" {
+		return nil, errors.New("resume session id is empty")
+	}
+	if len(input.Messages) > 0 {
+		return nil, errors.New("resume input cannot contain messages")
+	}
+	unlock, err := a.sessions.Lock(ctx, input.SessionID)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	cp, err := a.memory.Checkpoint(input.SessionID)
+	if err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	defer func() {
+		if err != nil {
+			err = a.rollback(cp, err)
+		}
+	}()
+	t := newTurn(input)
+	if err = a.mergeInputMeta(t); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	if output, err = a.resume(ctx, t, approvals); err != nil {
+		return output, a.handleError(ctx, err)
+	}
+	return
+}
+
+func (a *Agent) resume(ctx context.Context, t *turn, approvals []Approval) (*InteractOutput, error) {
+	pending, err := a.pendingToolCalls(t.input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, ErrNoPendingToolCalls
+	}
+	approved := make(map[string]bool)
+	reasons := make(map[string]string)
+	for _, approval := range approvals {
+		approved[approval.ToolCallID] = approval.Approved
+		reasons[approval.ToolCallID] = approval.Reason
+	}
+	var calls []message.ToolCall
+	for _, toolCall := range pending {
+		if approved[toolCall.ID] {
+			calls = append(calls, toolCall)
+			continue
+		}
+		reason := reasons[toolCall.ID]
+		if reason == "" {
+			reason = "no reason given"
+		}
+		msg := newToolErrorMessage(&toolCall, fmt.Errorf("tool call rejected by user: %s", reason))
+		if err = a.memory.AddMessage(t.input.SessionID, msg); err != nil {
+			return t.output, err
+		}
+		t.addToolResult(*msg)
+	}
+	t.items = a.ability.Items()
+	if err = a.execToolCalls(ctx, t, calls); err != nil {
+		return t.output, err
+	}
+	// 计划执行模式下继续执行暂停的步骤和之后的步骤
+	if a.planning != nil {
+		plan, err := a.GetPlan(t.input.SessionID)
+		if err != nil {
+			return t.output, err
+		}
+		if plan != nil && plan.runningStep() >= 0 {
+			return a.executePlan(ctx, t, plan, plan.runningStep())
+		}
+	}
+	return a.call(ctx, t)
+}
+
+// pendingToolCalls 从记忆中查找等待确认的工具调用
+// 即最后一条带有工具调用的assistant消息中，还没有对应结果的工具调用
+func (a *Agent) pendingToolCalls(sessionID string) (res []message.ToolCall, err error) {
+	messages, err := a.ListMessages(sessionID, pendingMessagesLimit)
+	if err != nil {
+		return
+	}
+	answered := make(map[string]bool)
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role == message.RoleTool {
+			answered[msg.ToolCallID] = true
+			continue
+		}
+		if msg.Role != message.RoleAssistant || len(msg.ToolCalls) == 0 {
+			return nil, nil
+		}
+		for _, toolCall := range msg.ToolCalls {
+			if !answered[toolCall.ID] {
+				res = append(res, toolCall)
+			}
+		}
+		return
+	}
+	return
+}
+
+// splitApprovalToolCalls 将工具调用分为需要人工确认的和可以直接执行的
+func (a *Agent) splitApprovalToolCalls(t *turn, toolCalls []message.ToolCall) (pending, ready []message.ToolCall) {
+	for _, toolCall := range toolCalls {
+		if _, tool, err := helpers.FindAbilityTool(t.items, toolCall.ToolID); err == nil && tool.RequireApproval {
+			pending = append(pending, toolCall)
+		} else {
+			ready = append(ready, toolCall)
+		}
+	}
+	return
+}
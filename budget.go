@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"github.com/deep-project/agent/pkg/budget"
+)
+
+// SetBudget 设置用量限额，每次调用思维和工具前检查，超出后中断交互并返回 ErrBudgetExceeded 和部分结果
+// 未设置存储时使用内存存储
+func (a *Agent) SetBudget(b *budget.Budget) *Agent {
+	if b != nil && b.Store == nil {
+		b.Store = budget.NewMemoryStore()
+	}
+	a.budget = b
+	return a
+}
+
+// checkBudget 检查会话是否已经用完限额
+func (a *Agent) checkBudget(sessionID string) error {
+	if a.budget == nil {
+		return nil
+	}
+	tenant, err := a.sessionTenant(sessionID)
+	if err != nil {
+		return err
+	}
+	return a.budget.Check(sessionID, tenant)
+}
+
+// addBudget 累加会话的使用量
+func (a *Agent) addBudget(sessionID string, spend budget.Spend) error {
+	if a.budget == nil {
+		return nil
+	}
+	tenant, err := a.sessionTenant(sessionID)
+	if err != nil {
+		return err
+	}
+	return a.budget.Add(sessionID, tenant, spend)
+}
+
+// reserveToolCallBudget 检查限额并计入一次工具调用
+// 并发执行的工具调用需要保证检查和计入之间不被其他调用插入
+func (a *Agent) reserveToolCallBudget(sessionID string) error {
+	if a.budget == nil {
+		return nil
+	}
+	a.budgetMu.Lock()
+	defer a.budgetMu.Unlock()
+	if err := a.checkBudget(sessionID); err != nil {
+		return err
+	}
+	return a.addBudget(sessionID, budget.Spend{ToolCalls: 1})
+}
+
+// sessionTenant 从会话meta中获取租户
+func (a *Agent) sessionTenant(sessionID string) (string, error) {
+	meta, err := a.memory.GetMeta(sessionID)
+	if err != nil {
+		return "", err
+	}
+	tenant, _ := meta[a.budget.TenantKey()].(string)
+	return tenant, nil
+}
@@ -0,0 +1,187 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/deep-project/agent/internal/helpers"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+const (
+	DefaultCompactionKeep   = 10 // 默认压缩时保留原文的最近消息数
+	DefaultCompactionPrompt = "Summarize the conversation below for your own future reference. " +
+		"Keep every fact, decision, agreement, open question and user preference that may matter later. " +
+		"Reply with the summary only."
+	summaryPrefix = "Summary of the earlier conversation:\n"
+)
+
+// CompactionOptions 对话压缩设置
+// 当会话的有效历史超过阈值时，较早的消息会由思维概括成一条摘要消息存入记忆，
+// 之后传给思维的上下文为摘要加最近的消息
+// 使用 MemorySimpleAdapter 时，阈值应小于 MaxSize，否则消息会在压缩前被删除
+type CompactionOptions struct {
+	Threshold       int    // 有效历史消息数超过该值时压缩，为0则不按消息数判断
+	ThresholdTokens int    // 有效历史token数超过该值时压缩，为0则不按token数判断
+	Keep            int    // 保留原文的最近消息数，为0则使用 DefaultCompactionKeep
+	Prompt          string // 摘要提示词，为空则使用 DefaultCompactionPrompt
+}
+
+// SetCompaction 设置对话压缩，为nil则不压缩
+func (a *Agent) SetCompaction(opt *CompactionOptions) *Agent {
+	a.compaction = opt
+	return a
+}
+
+// compact 检查会话的有效历史，超过阈值时压缩
+func (a *Agent) compact(ctx context.Context, t *turn) error {
+	sessionID := t.input.SessionID
+	opt := a.compaction
+	if opt == nil || (opt.Threshold <= 0 && opt.ThresholdTokens <= 0) {
+		return nil
+	}
+	stored, err := a.ListMessages(sessionID, 0)
+	if err != nil {
+		return err
+	}
+	view := summaryView(stored)
+	if !a.exceedsCompaction(stored, view) {
+		return nil
+	}
+	keep := opt.Keep
+	if keep <= 0 {
+		keep = DefaultCompactionKeep
+	}
+	// 保留的消息不能以tool消息开头，否则工具调用与结果会被拆开
+	split := max(len(view)-keep, 0)
+	for split > 0 && split < len(view) && stored[view[split]].Role == message.RoleTool {
+		split--
+	}
+	var span []message.Message
+	for _, i := range view[:split] {
+		if msg := stored[i]; msg.Summary || msg.Role != message.RoleSystem {
+			span = append(span, msg)
+		}
+	}
+	if len(span) == 0 {
+		return nil
+	}
+
+	if err = a.checkBudget(sessionID); err != nil {
+		return err
+	}
+	prompt := opt.Prompt
+	if prompt == "" {
+		prompt = DefaultCompactionPrompt
+	}
+	resp, err := a.wrapMindCall(a.mind.CallContext)(ctx, &mind.CallOptions{Messages: []message.Message{
+		{Role: message.RoleSystem, Contents: []message.Content{message.NewMessageWithContentText(prompt)}},
+		{Role: message.RoleUser, Contents: []message.Content{message.NewMessageWithContentText(transcript(span))}},
+	}})
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return errors.New("No response received")
+	}
+	if err = a.recordUsage(t, resp); err != nil {
+		return err
+	}
+	summary := helpers.JoinTextMessageContents(resp.Message.Contents)
+	if summary == "" {
+		return errors.New("summary is empty")
+	}
+	msg := message.Message{
+		Role:     message.RoleSystem,
+		Contents: []message.Content{message.NewMessageWithContentText(summaryPrefix + summary)},
+		Summary:  true,
+	}
+	if split < len(view) {
+		msg.SummaryKeep = len(stored) - view[split]
+	}
+	return a.memory.AddMessage(sessionID, &msg)
+}
+
+func (a *Agent) exceedsCompaction(stored []message.Message, view []int) bool {
+	if a.compaction.Threshold > 0 && len(view) > a.compaction.Threshold {
+		return true
+	}
+	if a.compaction.ThresholdTokens > 0 {
+		tokenizer := a.mind.Tokenizer()
+		var n int
+		for _, i := range view {
+			n += tokenizer.CountMessageTokens(&stored[i])
+		}
+		return n > a.compaction.ThresholdTokens
+	}
+	return false
+}
+
+// applySummary 用最后一条摘要替代被概括的消息
+func applySummary(messages []message.Message) []message.Message {
+	view := summaryView(messages)
+	if len(view) == len(messages) {
+		return messages
+	}
+	res := make([]message.Message, 0, len(view))
+	for _, i := range view {
+		res = append(res, messages[i])
+	}
+	return res
+}
+
+// summaryView 返回有效历史在消息列表中的位置
+// 有效历史为：被概括部分中的system消息、最后一条摘要、摘要之前保留原文的消息、摘要之后的消息
+func summaryView(messages []message.Message) (res []int) {
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Summary {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		for i := range messages {
+			res = append(res, i)
+		}
+		return
+	}
+	keepStart := max(last-messages[last].SummaryKeep, 0)
+	for i := 0; i < keepStart; i++ {
+		if messages[i].Role == message.RoleSystem && !messages[i].Summary {
+			res = append(res, i)
+		}
+	}
+	res = append(res, last)
+	for i := keepStart; i < len(messages); i++ {
+		if i != last && !messages[i].Summary {
+			res = append(res, i)
+		}
+	}
+	return
+}
+
+// transcript 将消息转换为文本记录，用于生成摘要
+func transcript(messages []message.Message) string {
+	var b strings.Builder
+	for _, msg := range messages {
+		text := helpers.JoinTextMessageContents(msg.Contents)
+		switch {
+		case msg.Summary:
+			fmt.Fprintf(&b, "[earlier summary]\n%s\n\n", strings.TrimPrefix(text, summaryPrefix))
+		case msg.Role == message.RoleTool:
+			fmt.Fprintf(&b, "[tool result]\n%s\n\n", text)
+		default:
+			if text != "" {
+				fmt.Fprintf(&b, "[%s]\n%s\n\n", msg.Role, text)
+			}
+			for _, toolCall := range msg.ToolCalls {
+				fmt.Fprintf(&b, "[%s called tool %s]\n%s\n\n", msg.Role, toolCall.ToolID, toolCall.Arguments.String())
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/budget"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+)
+
+const delegateQuestionParameter = "question"
+
+type AgentAbilityOptions struct {
+	Name            string // 名称，工具名为 ask_<Name>
+	Description     string // 描述，协调者根据描述决定是否委派
+	MessagesLimit   int    // 子会话的消息数限制
+	MaxSteps        int    // 子会话的最大步数，为0则使用子agent的设置
+	RequireApproval bool   // 委派前需要人工确认
+}
+
+// AgentAbility 将agent包装成能力，赋予其他agent后可以委派任务
+// 每次工具调用都会开启一个子会话，子agent的最终回复作为工具调用结果返回
+type AgentAbility struct {
+	agent   *Agent
+	options *AgentAbilityOptions
+}
+
+// AsAbility 将agent作为能力提供给其他agent
+func (a *Agent) AsAbility(options *AgentAbilityOptions) *AgentAbility {
+	return &AgentAbility{agent: a, options: options}
+}
+
+func (d *AgentAbility) Name() string {
+	return d.options.Name
+}
+
+func (d *AgentAbility) Description() string {
+	return d.options.Description
+}
+
+func (d *AgentAbility) Enable() bool {
+	return true
+}
+
+func (d *AgentAbility) ToolName() string {
+	return "ask_" + d.options.Name
+}
+
+func (d *AgentAbility) Tools() ([]ability.Tool, error) {
+	return []ability.Tool{{
+		Name:        d.ToolName(),
+		Enable:      true,
+		Description: d.options.Description,
+		Parameters: []ability.ToolParameter{{
+			Name:        delegateQuestionParameter,
+			Type:        "string",
+			Description: "the task or question for " + d.options.Name + ", including all necessary context",
+			Required:    true,
+		}},
+		RequireApproval: d.options.RequireApproval,
+	}}, nil
+}
+
+func (d *AgentAbility) CallTool(opt *ability.CallToolOptions) (*message.Message, error) {
+	return d.CallToolContext(context.Background(), opt)
+}
+
+func (d *AgentAbility) CallToolContext(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+	if opt.Name != d.ToolName() {
+		return nil, ability.ErrAbilityToolNotFound
+	}
+	var question string
+	if opt.Args != nil {
+		question, _ = (*opt.Args)[delegateQuestionParameter].(string)
+	}
+	if question == "" {
+		return nil, errors.New("question is required")
+	}
+	// 子会话继承上级会话的meta（如用户和租户），工具和用量限额使用相同的身份
+	meta := maps.Clone(opt.Meta)
+	delete(meta, PlanMetaKey)
+	delete(meta, TeamActiveAgentMeta)
+	t := newTurn(&InteractInput{
+		SessionID:     SubSessionID(opt.SessionID, opt.ToolCallID),
+		Messages:      []message.Message{{Role: message.RoleUser, Contents: []message.Content{message.NewMessageWithContentText(question)}}},
+		MessagesLimit: d.options.MessagesLimit,
+		MaxSteps:      d.options.MaxSteps,
+		Meta:          meta,
+	})
+	parent := parentCallFromContext(ctx)
+	if parent != nil {
+		t.emit = parent.forward(opt.ToolCallID)
+	}
+	output, err := d.agent.interact(ctx, t)
+	if parent != nil && output != nil {
+		if e := parent.addUsage(output.Usage); e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if output.Status == InteractStatusPendingApproval {
+		return nil, fmt.Errorf("%s is waiting for approval of tool calls in session %s", d.options.Name, output.SessionID)
+	}
+	return &message.Message{Role: message.RoleTool, Contents: output.Message.Contents}, nil
+}
+
+// SubSessionID 委派产生的子会话ID，由上级会话ID和工具调用ID组成
+func SubSessionID(parentSessionID, toolCallID string) string {
+	return parentSessionID + "/" + toolCallID
+}
+
+type parentCallKey struct{}
+
+// parentCall 执行工具调用的上级agent和交互，子agent通过它上报用量和事件
+type parentCall struct {
+	agent *Agent
+	turn  *turn
+	mu    sync.Mutex // 并发执行的子agent共用同一个上级交互
+}
+
+func withParentCall(ctx context.Context, a *Agent, t *turn) context.Context {
+	return context.WithValue(ctx, parentCallKey{}, &parentCall{agent: a, turn: t})
+}
+
+func parentCallFromContext(ctx context.Context) *parentCall {
+	p, _ := ctx.Value(parentCallKey{}).(*parentCall)
+	return p
+}
+
+// addUsage 将子会话的用量累加到上级交互和会话
+func (p *parentCall) addUsage(u usage.Usage) error {
+	if u.IsZero() {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sessionID := p.turn.input.SessionID
+	p.turn.output.Usage.Add(u)
+	if err := p.agent.addBudget(sessionID, budget.Spend{Tokens: u.TotalTokens, Cost: u.Cost}); err != nil {
+		return err
+	}
+	return p.agent.memory.AddUsage(sessionID, u)
+}
+
+// forward 将子会话的事件转发到上级交互的事件流
+func (p *parentCall) forward(toolCallID string) func(StreamEvent) {
+	if p.turn.emit == nil {
+		return nil
+	}
+	return func(event StreamEvent) {
+		if event.ParentToolCallID == "" {
+			event.ParentToolCallID = toolCallID
+		}
+		p.turn.emit(event)
+	}
+}
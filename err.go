@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/deep-project/agent/pkg/budget"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+var (
+	ErrMaxStepsExceeded      = errors.New("max steps exceeded")
+	ErrRepeatedToolCall      = errors.New("repeated identical tool call")
+	ErrToolResultError       = errors.New("tool returned an error result")
+	ErrNoPendingToolCalls    = errors.New("no pending tool calls to resume")
+	ErrInvalidResponse       = errors.New("response does not match the required format")
+	ErrMaxHandoffsExceeded   = errors.New("max handoffs exceeded")
+	ErrTeamAgentNotFound     = errors.New("team agent not found")
+	ErrNoMessageToRegenerate = errors.New("no user message to regenerate from")
+	ErrMessageNotEditable    = errors.New("only user messages can be edited")
+	ErrToolResultUnavailable = errors.New("tool result unavailable")
+	ErrBudgetExceeded        = budget.ErrBudgetExceeded
+)
+
+// ToolCallError 工具调用失败
+type ToolCallError struct {
+	ToolCall message.ToolCall
+	Err      error
+}
+
+func (e *ToolCallError) Error() string {
+	return fmt.Sprintf("tool call %s (%s) failed: %s", e.ToolCall.ID, e.ToolCall.ToolID, e.Err.Error())
+}
+
+func (e *ToolCallError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// SetMergeSameRoleMessages 合并相邻的同角色消息，用于要求user和assistant交替出现的思维
+func (a *Agent) SetMergeSameRoleMessages(enable bool) *Agent {
+	a.mergeSameRole = enable
+	return a
+}
+
+// sanitizeMessages 修复发送给思维的历史消息，保证工具调用与工具结果一一对应
+// 没有对应工具调用的tool消息会被丢弃（包括裁切后以tool开头的消息和重复的结果），
+// 没有结果的工具调用补充一条"tool result unavailable"的错误结果
+func sanitizeMessages(msgs []message.Message, mergeSameRole bool) []message.Message {
+	var (
+		res     []message.Message
+		calls   []message.ToolCall // 当前assistant消息中还没有结果的工具调用
+		answers = make(map[string]bool)
+	)
+	// closeCalls 为没有结果的工具调用补充错误结果
+	closeCalls := func() {
+		for i := range calls {
+			if !answers[calls[i].ID] {
+				res = append(res, *newToolErrorMessage(&calls[i], ErrToolResultUnavailable))
+			}
+		}
+		calls = nil
+		clear(answers)
+	}
+	for _, msg := range msgs {
+		if msg.Role == message.RoleTool {
+			if !answers[msg.ToolCallID] && slices.ContainsFunc(calls, func(c message.ToolCall) bool { return c.ID == msg.ToolCallID }) {
+				answers[msg.ToolCallID] = true
+				res = append(res, msg)
+			}
+			continue
+		}
+		closeCalls()
+		if msg.Role == message.RoleAssistant {
+			calls = msg.ToolCalls
+		}
+		if mergeSameRole && len(res) > 0 && canMergeMessages(res[len(res)-1], msg) {
+			last := &res[len(res)-1]
+			last.Contents = append(slices.Clone(last.Contents), msg.Contents...)
+			last.ToolCalls = msg.ToolCalls
+			continue
+		}
+		res = append(res, msg)
+	}
+	closeCalls()
+	return res
+}
+
+// canMergeMessages 相邻的user消息或者没有工具调用的assistant消息可以合并
+func canMergeMessages(prev, msg message.Message) bool {
+	if prev.Role != msg.Role || len(prev.ToolCalls) > 0 {
+		return false
+	}
+	return msg.Role == message.RoleUser || msg.Role == message.RoleAssistant
+}
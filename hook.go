@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+// MindCallFunc 调用思维
+type MindCallFunc func(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error)
+
+// MindMiddleware 思维调用中间件，可以检查或改写调用参数和回复，也可以不调用next直接返回
+type MindMiddleware func(next MindCallFunc) MindCallFunc
+
+// ToolCallFunc 调用工具
+type ToolCallFunc func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error)
+
+// ToolMiddleware 工具调用中间件，可以检查或改写调用参数和结果，也可以不调用next直接返回
+type ToolMiddleware func(next ToolCallFunc) ToolCallFunc
+
+// ErrorHook 交互出错时调用，返回的错误替代原错误，返回nil则保留原错误
+type ErrorHook func(ctx context.Context, err error) error
+
+// Hooks 交互过程中的钩子，未设置的钩子会被忽略
+type Hooks struct {
+	BeforeMindCall func(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error)           // 返回不为空的回复则跳过思维调用
+	AfterMindCall  func(ctx context.Context, opt *mind.CallOptions, resp *mind.CallResponse) error        // 可以直接修改回复
+	BeforeToolCall func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error)      // 返回不为空的结果则跳过工具调用
+	AfterToolCall  func(ctx context.Context, opt *ability.CallToolOptions, result *message.Message) error // 可以直接修改结果
+	OnError        ErrorHook
+}
+
+// UseMindMiddleware 添加思维调用中间件，先添加的在外层
+func (a *Agent) UseMindMiddleware(middlewares ...MindMiddleware) *Agent {
+	a.mindMiddlewares = append(a.mindMiddlewares, middlewares...)
+	return a
+}
+
+// UseToolMiddleware 添加工具调用中间件，先添加的在外层
+func (a *Agent) UseToolMiddleware(middlewares ...ToolMiddleware) *Agent {
+	a.toolMiddlewares = append(a.toolMiddlewares, middlewares...)
+	return a
+}
+
+// UseErrorHook 添加交互出错时的钩子
+func (a *Agent) UseErrorHook(hooks ...ErrorHook) *Agent {
+	a.errorHooks = append(a.errorHooks, hooks...)
+	return a
+}
+
+// UseHooks 添加钩子，钩子会被转换为中间件
+func (a *Agent) UseHooks(hooks *Hooks) *Agent {
+	if hooks.BeforeMindCall != nil || hooks.AfterMindCall != nil {
+		a.UseMindMiddleware(func(next MindCallFunc) MindCallFunc {
+			return func(ctx context.Context, opt *mind.CallOptions) (resp *mind.CallResponse, err error) {
+				if hooks.BeforeMindCall != nil {
+					if resp, err = hooks.BeforeMindCall(ctx, opt); err != nil || resp != nil {
+						return
+					}
+				}
+				if resp, err = next(ctx, opt); err != nil {
+					return
+				}
+				if hooks.AfterMindCall != nil && resp != nil {
+					err = hooks.AfterMindCall(ctx, opt, resp)
+				}
+				return
+			}
+		})
+	}
+	if hooks.BeforeToolCall != nil || hooks.AfterToolCall != nil {
+		a.UseToolMiddleware(func(next ToolCallFunc) ToolCallFunc {
+			return func(ctx context.Context, opt *ability.CallToolOptions) (result *message.Message, err error) {
+				if hooks.BeforeToolCall != nil {
+					if result, err = hooks.BeforeToolCall(ctx, opt); err != nil || result != nil {
+						return
+					}
+				}
+				if result, err = next(ctx, opt); err != nil {
+					return
+				}
+				if hooks.AfterToolCall != nil && result != nil {
+					err = hooks.AfterToolCall(ctx, opt, result)
+				}
+				return
+			}
+		})
+	}
+	if hooks.OnError != nil {
+		a.UseErrorHook(hooks.OnError)
+	}
+	return a
+}
+
+// wrapMindCall 使用中间件包装思维调用
+func (a *Agent) wrapMindCall(call MindCallFunc) MindCallFunc {
+	for i := len(a.mindMiddlewares) - 1; i >= 0; i-- {
+		call = a.mindMiddlewares[i](call)
+	}
+	return call
+}
+
+// wrapToolCall 使用中间件包装工具调用
+func (a *Agent) wrapToolCall(call ToolCallFunc) ToolCallFunc {
+	for i := len(a.toolMiddlewares) - 1; i >= 0; i-- {
+		call = a.toolMiddlewares[i](call)
+	}
+	return call
+}
+
+// handleError 依次调用错误钩子
+func (a *Agent) handleError(ctx context.Context, err error) error {
+	for _, hook := range a.errorHooks {
+		if e := hook(ctx, err); e != nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// InstructionsData 渲染指令模板时可用的数据
+// 例如：你是{{.Meta.company}}的客服，当前用户是{{.Meta.user_name}}，今天是{{.Date}}
+type InstructionsData struct {
+	SessionID string
+	Meta      ability.Meta
+	Now       time.Time
+	Date      string // 当前日期，格式 2006-01-02
+}
+
+// GrantInstructions 给智能体赋予指令（人设）
+// 指令会在每次调用思维时作为system消息放在最前面，不会存入记忆，也不计入消息数限制
+// 支持 text/template 模板语法，可用数据见 InstructionsData
+func (a *Agent) GrantInstructions(instructions string) *Agent {
+	a.instructions = instructions
+	return a
+}
+
+// renderInstructions 渲染本轮交互的指令，InteractInput 中的指令优先
+func (a *Agent) renderInstructions(t *turn) (*message.Message, error) {
+	instructions := a.instructions
+	if t.input.Instructions != "" {
+		instructions = t.input.Instructions
+	}
+	if instructions == "" {
+		return nil, nil
+	}
+	if strings.Contains(instructions, "{{") {
+		tpl, err := template.New("instructions").Parse(instructions)
+		if err != nil {
+			return nil, err
+		}
+		meta, err := a.memory.GetMeta(t.input.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		var b strings.Builder
+		if err = tpl.Execute(&b, &InstructionsData{SessionID: t.input.SessionID, Meta: meta, Now: now, Date: now.Format(time.DateOnly)}); err != nil {
+			return nil, err
+		}
+		instructions = b.String()
+	}
+	return &message.Message{
+		Role:     message.RoleSystem,
+		Contents: []message.Content{message.NewMessageWithContentText(instructions)},
+	}, nil
+}
@@ -22,6 +22,19 @@ func JoinTextMessageContents(contents []message.Content) string {
 	return res
 }
 
+// TrimCodeFence 去掉文本首尾的空白和markdown代码块标记
+func TrimCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(text, "```")
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		text = text[i+1:] // 去掉语言标记，例如 ```json
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
+
 // ability items 转换成 Mind Tools
 func AbilityItemsToMindTools(items []ability.Item) (res []mind.Tool, err error) {
 	for i, item := range items {
@@ -37,6 +50,23 @@ func AbilityItemsToMindTools(items []ability.Item) (res []mind.Tool, err error)
 	return res, nil
 }
 
+// 根据 mind tool id 查找对应的 ability item 和 tool
+func FindAbilityTool(items []ability.Item, toolID string) (*ability.Item, *ability.Tool, error) {
+	index, toolName, err := ParseMindToolID(toolID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if index < 0 || index > len(items)-1 {
+		return nil, nil, ability.ErrAbilityItemNotFound
+	}
+	for _, tool := range items[index].Tools() {
+		if tool.Name == toolName {
+			return &items[index], &tool, nil
+		}
+	}
+	return nil, nil, ability.ErrAbilityToolNotFound
+}
+
 // 使用ability index和tool name 生成 mind tool id
 func GenerateMindToolID(abilityIndex int, toolName string) string {
 	return fmt.Sprintf("%d-%s", abilityIndex, toolName)
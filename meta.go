@@ -0,0 +1,28 @@
+package agent
+
+import (
+	"github.com/deep-project/agent/pkg/ability"
+)
+
+// GetMeta 获取会话的meta
+func (a *Agent) GetMeta(sessionID string) (ability.Meta, error) {
+	return a.memory.GetMeta(sessionID)
+}
+
+// SetMeta 保存会话的meta，替换原有的meta
+func (a *Agent) SetMeta(sessionID string, meta ability.Meta) error {
+	return a.memory.SetMeta(sessionID, meta)
+}
+
+// UpdateMeta 合并到会话的meta，值为nil则删除对应的key
+func (a *Agent) UpdateMeta(sessionID string, values ability.Meta) error {
+	return a.memory.UpdateMeta(sessionID, values)
+}
+
+// mergeInputMeta 将本轮交互携带的meta合并到会话
+func (a *Agent) mergeInputMeta(t *turn) error {
+	if len(t.input.Meta) == 0 {
+		return nil
+	}
+	return a.memory.UpdateMeta(t.input.SessionID, t.input.Meta)
+}
@@ -1,24 +1,29 @@
 package ability
 
 import (
+	"context"
 	"sync"
 
 	"github.com/deep-project/agent/pkg/message"
 )
 
+// Ability 能力列表
+// 修改时采用写时复制，Items 返回的快照不会被之后的修改影响，可以在交互过程中安全地增减能力
 type Ability struct {
 	items []Item
 	mu    sync.RWMutex
 }
 
 func (a *Ability) Add(handler Handler) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
 	item, err := newItem(handler)
 	if err != nil {
 		return err
 	}
-	a.items = append(a.items, *item)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	items := make([]Item, len(a.items), len(a.items)+1)
+	copy(items, a.items)
+	a.items = append(items, *item)
 	return nil
 }
 
@@ -28,28 +33,29 @@ func (a *Ability) Clear() {
 	a.items = []Item{}
 }
 
+// Items 返回当前能力列表的快照，调用方不应修改
 func (a *Ability) Items() []Item {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.items
 }
 
-func (a *Ability) Call(index int, toolName string, args *message.ToolCallArguments, meta Meta) (_ *message.Message, err error) {
+func (a *Ability) Call(index int, toolName string, args *message.ToolCallArguments, meta Meta) (*message.Message, error) {
+	return a.CallContext(context.Background(), index, toolName, args, meta)
+}
+
+func (a *Ability) CallContext(ctx context.Context, index int, toolName string, args *message.ToolCallArguments, meta Meta) (_ *message.Message, err error) {
 	item, err := a.getItem(index)
 	if err != nil {
 		return
 	}
-	if item.handler == nil {
-		return nil, ErrAbilityHandlerNotDefined
-	}
-	return item.handler.CallTool(&CallToolOptions{Name: toolName, Args: args, Meta: meta})
+	return item.CallTool(ctx, &CallToolOptions{Name: toolName, Args: args, Meta: meta})
 }
 
 func (a *Ability) getItem(index int) (*Item, error) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	if index > len(a.items)-1 {
+	items := a.Items()
+	if index < 0 || index > len(items)-1 {
 		return nil, ErrAbilityItemNotFound
 	}
-	return &a.items[index], nil
+	return &items[index], nil
 }
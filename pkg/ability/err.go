@@ -5,4 +5,5 @@ import "errors"
 var (
 	ErrAbilityHandlerNotDefined = errors.New("ability handler is not defined")
 	ErrAbilityItemNotFound      = errors.New("ability item not found")
+	ErrAbilityToolNotFound      = errors.New("ability tool not found")
 )
@@ -1,6 +1,8 @@
 package ability
 
 import (
+	"context"
+
 	"github.com/deep-project/agent/pkg/message"
 )
 
@@ -12,10 +14,29 @@ type Handler interface {
 	CallTool(opt *CallToolOptions) (*message.Message, error)
 }
 
+// ContextHandler 支持context的handler，实现后可以通过context取消或超时中断工具调用
+type ContextHandler interface {
+	Handler
+	CallToolContext(ctx context.Context, opt *CallToolOptions) (*message.Message, error)
+}
+
+// CallHandler 调用handler的工具，如果handler不支持context，则在调用前检查context是否已取消
+func CallHandler(ctx context.Context, handler Handler, opt *CallToolOptions) (*message.Message, error) {
+	if h, ok := handler.(ContextHandler); ok {
+		return h.CallToolContext(ctx, opt)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return handler.CallTool(opt)
+}
+
 type CallToolOptions struct {
-	Name string
-	Args *message.ToolCallArguments
-	Meta Meta
+	Name       string
+	Args       *message.ToolCallArguments
+	Meta       Meta
+	SessionID  string // 会话ID
+	ToolCallID string // 工具调用ID
 }
 
 type Meta map[string]any
@@ -23,3 +44,14 @@ type Meta map[string]any
 func NewMeta() Meta {
 	return make(map[string]any)
 }
+
+// Merge 合并values，值为nil则删除对应的key
+func (m Meta) Merge(values Meta) {
+	for k, v := range values {
+		if v == nil {
+			delete(m, k)
+		} else {
+			m[k] = v
+		}
+	}
+}
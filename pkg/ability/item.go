@@ -1,5 +1,11 @@
 package ability
 
+import (
+	"context"
+
+	"github.com/deep-project/agent/pkg/message"
+)
+
 type Item struct {
 	Name        string // name
 	Description string // 描述
@@ -29,6 +35,19 @@ func (i *Item) Tools() []Tool {
 	return i.tools
 }
 
+// Handler 能力的handler
+func (i *Item) Handler() Handler {
+	return i.handler
+}
+
+// CallTool 调用能力的工具
+func (i *Item) CallTool(ctx context.Context, opt *CallToolOptions) (*message.Message, error) {
+	if i.handler == nil {
+		return nil, ErrAbilityHandlerNotDefined
+	}
+	return CallHandler(ctx, i.handler, opt)
+}
+
 // 初始化tools,如果外部接口tools有更新，可以重新初始化
 func (i *Item) InitTools() (err error) {
 	i.tools, err = i.handler.Tools()
@@ -1,10 +1,12 @@
 package ability
 
 type Tool struct {
-	Name        string
-	Enable      bool // 启用
-	Description string
-	Parameters  []ToolParameter // 参数
+	Name            string
+	Enable          bool // 启用
+	Description     string
+	Parameters      []ToolParameter // 参数
+	Serial          bool            // 不可与其他工具并发执行
+	RequireApproval bool            // 执行前需要人工确认
 }
 
 // Parameters Convert To JSON Schema
@@ -14,6 +16,9 @@ func (t *Tool) ParametersJSONSchema() *JSONSchema {
 		Properties: t.Parameters,
 		Required:   []string{},
 	}
+	if len(t.Parameters) == 0 {
+		res.Properties = map[string]any{} // 没有参数的工具也需要properties字段
+	}
 	for _, p := range t.Parameters {
 		if p.Required {
 			res.Required = append(res.Required, p.Name)
@@ -0,0 +1,134 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+type Scope string
+
+const (
+	ScopeSession Scope = "session" // 每个会话
+	ScopeDaily   Scope = "daily"   // 每个租户每天，没有租户时为所有会话每天
+	ScopeTenant  Scope = "tenant"  // 每个租户
+)
+
+// Limit 限额，为0的项不限制
+type Limit struct {
+	Tokens    int     `json:"tokens"`
+	Cost      float64 `json:"cost"`
+	ToolCalls int     `json:"tool_calls"`
+}
+
+func (l Limit) IsZero() bool {
+	return l == Limit{}
+}
+
+// Spend 已使用量
+type Spend struct {
+	Tokens    int     `json:"tokens"`
+	Cost      float64 `json:"cost"`
+	ToolCalls int     `json:"tool_calls"`
+}
+
+func (s *Spend) Add(o Spend) {
+	s.Tokens += o.Tokens
+	s.Cost += o.Cost
+	s.ToolCalls += o.ToolCalls
+}
+
+// Exceeded 是否已经用完限额
+func (s Spend) Exceeded(l Limit) bool {
+	return (l.Tokens > 0 && s.Tokens >= l.Tokens) ||
+		(l.Cost > 0 && s.Cost >= l.Cost) ||
+		(l.ToolCalls > 0 && s.ToolCalls >= l.ToolCalls)
+}
+
+// ExceededError 超出限额
+type ExceededError struct {
+	Scope Scope
+	Key   string
+	Limit Limit
+	Spend Spend
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("%s budget exceeded for %s: spent %+v, limit %+v", e.Scope, e.Key, e.Spend, e.Limit)
+}
+
+func (e *ExceededError) Is(target error) bool {
+	return target == ErrBudgetExceeded
+}
+
+// Store 保存已使用量，重启后限额仍然有效需使用持久化的存储
+type Store interface {
+	Get(key string) (Spend, error)
+	Add(key string, spend Spend) error
+}
+
+// Budget 限额设置
+type Budget struct {
+	Session       Limit
+	Daily         Limit
+	Tenant        Limit
+	TenantMetaKey string           // 从会话meta中获取租户的key，为空则使用 "tenant"
+	Store         Store            // 为空则使用内存存储
+	Now           func() time.Time // 获取当前时间，用于按天统计，为空则使用 time.Now
+}
+
+func (b *Budget) TenantKey() string {
+	if b.TenantMetaKey == "" {
+		return "tenant"
+	}
+	return b.TenantMetaKey
+}
+
+// Check 检查会话和租户是否已经用完限额，用完则返回 ExceededError
+func (b *Budget) Check(sessionID, tenant string) error {
+	for _, scope := range b.scopes(sessionID, tenant) {
+		spend, err := b.Store.Get(scope.key)
+		if err != nil {
+			return err
+		}
+		if spend.Exceeded(scope.limit) {
+			return &ExceededError{Scope: scope.scope, Key: scope.key, Limit: scope.limit, Spend: spend}
+		}
+	}
+	return nil
+}
+
+// Add 累加会话和租户的使用量
+func (b *Budget) Add(sessionID, tenant string, spend Spend) error {
+	for _, scope := range b.scopes(sessionID, tenant) {
+		if err := b.Store.Add(scope.key, spend); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type scopeLimit struct {
+	scope Scope
+	key   string
+	limit Limit
+}
+
+func (b *Budget) scopes(sessionID, tenant string) (res []scopeLimit) {
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	if !b.Session.IsZero() {
+		res = append(res, scopeLimit{ScopeSession, "session:" + sessionID, b.Session})
+	}
+	if !b.Daily.IsZero() {
+		res = append(res, scopeLimit{ScopeDaily, "daily:" + tenant + ":" + now().Format(time.DateOnly), b.Daily})
+	}
+	if !b.Tenant.IsZero() && tenant != "" {
+		res = append(res, scopeLimit{ScopeTenant, "tenant:" + tenant, b.Tenant})
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package budget
+
+import "sync"
+
+// MemoryStore 内存存储，重启后清空
+type MemoryStore struct {
+	spends map[string]Spend
+	mu     sync.RWMutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{spends: make(map[string]Spend)}
+}
+
+func (m *MemoryStore) Get(key string) (Spend, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.spends[key], nil
+}
+
+func (m *MemoryStore) Add(key string, spend Spend) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	total := m.spends[key]
+	total.Add(spend)
+	m.spends[key] = total
+	return nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// Checkpoint 会话在某一时刻的消息位置，用于回滚之后写入的消息
+// 使用设置了最大数量的适配器时，回滚前被挤出的最早消息无法恢复
+type Checkpoint struct {
+	SessionID string
+	LastID    string       // 最后一条消息的ID，按ID定位，不受最大数量挤出消息的影响
+	Count     int          // 消息数量，没有最后一条消息的ID时按数量回滚
+	Exists    bool         // 会话是否已经存在，不存在则回滚时删除整个会话
+	Meta      ability.Meta // 会话不存在时已经设置的meta，删除会话后恢复
+}
+
+// Checkpoint 记录会话当前的消息位置，只读取会话信息和最后一条消息
+func (m *Memory) Checkpoint(sessionID string) (*Checkpoint, error) {
+	cp := &Checkpoint{SessionID: sessionID}
+	s, err := m.GetSession(sessionID)
+	if err == nil {
+		cp.Exists, cp.Count = true, s.MessageCount
+		if cp.Count == 0 {
+			return cp, nil
+		}
+		last, err := m.ListMessages(sessionID, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(last) > 0 {
+			cp.LastID = last[len(last)-1].ID
+		}
+		return cp, nil
+	}
+	if !errors.Is(err, ErrSessionNotFound) {
+		return nil, err
+	}
+	if cp.Meta, err = m.GetMeta(sessionID); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Rollback 删除checkpoint之后写入的消息
+// 最后一条消息因为超出最大数量被挤出时，说明现有的消息都是之后写入的，全部删除
+// 新会话的第一轮交互失败时删除整个会话，只保留之前已经设置的meta和已经产生的用量
+func (m *Memory) Rollback(cp *Checkpoint) error {
+	if !cp.Exists {
+		u, err := m.GetUsage(cp.SessionID)
+		if err != nil {
+			return err
+		}
+		if err = m.DeleteSession(cp.SessionID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+			return err
+		}
+		if len(cp.Meta) > 0 {
+			if err = m.SetMeta(cp.SessionID, cp.Meta); err != nil {
+				return err
+			}
+		}
+		if !u.IsZero() {
+			return m.AddUsage(cp.SessionID, u)
+		}
+		return nil
+	}
+	exists, err := m.HasMessageSession(cp.SessionID)
+	if err != nil || !exists {
+		return err
+	}
+	messages, err := m.ListMessages(cp.SessionID, 0)
+	if err != nil {
+		return err
+	}
+	n := cp.Count
+	if cp.LastID != "" {
+		n = slices.IndexFunc(messages, func(msg message.Message) bool { return msg.ID == cp.LastID }) + 1
+	}
+	if n >= len(messages) {
+		return nil
+	}
+	return m.TruncateMessages(cp.SessionID, n)
+}
@@ -4,4 +4,7 @@ import "errors"
 
 var (
 	ErrMemoryHandlerNotDefined = errors.New("memory handler is not defined")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrSessionExists           = errors.New("session already exists")
+	ErrMessageNotFound         = errors.New("message not found")
 )
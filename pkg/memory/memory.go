@@ -1,15 +1,42 @@
 package memory
 
 import (
+	"context"
+	"time"
+
 	"github.com/deep-project/agent/pkg/ability"
 	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+
+	"github.com/google/uuid"
 )
 
 type Handler interface {
 	GetMeta(sessionID string) (ability.Meta, error)
+	SetMeta(sessionID string, meta ability.Meta) error      // 保存会话的meta，替换原有的meta
+	UpdateMeta(sessionID string, values ability.Meta) error // 合并到会话的meta，值为nil则删除对应的key
 	AddMessage(sessionID string, msg *message.Message) error
-	ListMessages(sessionID string, limit int) ([]message.Message, error)
-	HasMessageSession(sessionID string) (bool, error) // 消息对话是否存在
+	ListMessages(sessionID string, limit int) ([]message.Message, error) // 按时间顺序返回最近的limit条消息，limit小于等于0则返回全部
+	HasMessageSession(sessionID string) (bool, error)                    // 消息对话是否存在
+	AddUsage(sessionID string, u usage.Usage) error                      // 累加会话的用量
+	GetUsage(sessionID string) (usage.Usage, error)                      // 获取会话的累计用量
+
+	ListSessions(offset, limit int) (sessions []Session, total int, err error) // 按最后更新时间倒序列出会话，limit小于等于0则返回全部
+	GetSession(sessionID string) (*Session, error)                             // 获取会话信息，不存在则返回 ErrSessionNotFound
+	DeleteSession(sessionID string) error                                      // 删除会话的消息、meta和用量
+	RenameSession(sessionID, title string) error                               // 修改会话标题
+	ForkSession(sessionID, newSessionID string, n int) error                   // 复制前n条消息和meta到新会话，n小于等于0则复制全部消息
+
+	TruncateMessages(sessionID string, n int) error              // 只保留前n条消息，删除之后的全部消息
+	ReplaceMessage(sessionID string, msg *message.Message) error // 替换ID相同的消息，不存在则返回 ErrMessageNotFound
+}
+
+// ContextHandler 支持context的handler，实现后可以通过context取消或超时中断交互过程中的消息读写
+// 适用于数据库或网络存储，内置的适配器读写本地数据，不需要实现
+type ContextHandler interface {
+	Handler
+	AddMessageContext(ctx context.Context, sessionID string, msg *message.Message) error
+	ListMessagesContext(ctx context.Context, sessionID string, limit int) ([]message.Message, error)
 }
 
 type Memory struct {
@@ -31,26 +58,74 @@ func (m *Memory) GetMeta(sessionID string) (ability.Meta, error) {
 	return m.handler.GetMeta(sessionID)
 }
 
-func (m *Memory) AddMessages(sessionID string, messages []message.Message) (err error) {
+func (m *Memory) SetMeta(sessionID string, meta ability.Meta) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.SetMeta(sessionID, meta)
+}
+
+func (m *Memory) UpdateMeta(sessionID string, values ability.Meta) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.UpdateMeta(sessionID, values)
+}
+
+func (m *Memory) AddMessages(sessionID string, messages []message.Message) error {
+	return m.AddMessagesContext(context.Background(), sessionID, messages)
+}
+
+// AddMessagesContext 依次存入消息，每条消息复制后再生成ID和创建时间，不修改调用方的消息
+func (m *Memory) AddMessagesContext(ctx context.Context, sessionID string, messages []message.Message) (err error) {
 	for _, msg := range messages {
-		if err = m.AddMessage(sessionID, &msg); err != nil {
+		if err = m.AddMessageContext(ctx, sessionID, &msg); err != nil {
 			return
 		}
 	}
 	return nil
 }
 
+// AddMessage 存入消息，消息没有ID和创建时间时会生成
 func (m *Memory) AddMessage(sessionID string, msg *message.Message) error {
+	return m.AddMessageContext(context.Background(), sessionID, msg)
+}
+
+// AddMessageContext 存入消息，如果handler不支持context，则在写入前检查context是否已取消
+func (m *Memory) AddMessageContext(ctx context.Context, sessionID string, msg *message.Message) error {
 	if m.handler == nil {
 		return ErrMemoryHandlerNotDefined
 	}
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+	if h, ok := m.handler.(ContextHandler); ok {
+		return h.AddMessageContext(ctx, sessionID, msg)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	return m.handler.AddMessage(sessionID, msg)
 }
 
 func (m *Memory) ListMessages(sessionID string, limit int) ([]message.Message, error) {
+	return m.ListMessagesContext(context.Background(), sessionID, limit)
+}
+
+// ListMessagesContext 获取消息列表，如果handler不支持context，则在读取前检查context是否已取消
+func (m *Memory) ListMessagesContext(ctx context.Context, sessionID string, limit int) ([]message.Message, error) {
 	if m.handler == nil {
 		return nil, ErrMemoryHandlerNotDefined
 	}
+	if h, ok := m.handler.(ContextHandler); ok {
+		return h.ListMessagesContext(ctx, sessionID, limit)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	return m.handler.ListMessages(sessionID, limit)
 }
 
@@ -60,3 +135,66 @@ func (m *Memory) HasMessageSession(sessionID string) (bool, error) {
 	}
 	return m.handler.HasMessageSession(sessionID)
 }
+
+func (m *Memory) AddUsage(sessionID string, u usage.Usage) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.AddUsage(sessionID, u)
+}
+
+func (m *Memory) GetUsage(sessionID string) (usage.Usage, error) {
+	if m.handler == nil {
+		return usage.Usage{}, ErrMemoryHandlerNotDefined
+	}
+	return m.handler.GetUsage(sessionID)
+}
+
+func (m *Memory) ListSessions(offset, limit int) ([]Session, int, error) {
+	if m.handler == nil {
+		return nil, 0, ErrMemoryHandlerNotDefined
+	}
+	return m.handler.ListSessions(offset, limit)
+}
+
+func (m *Memory) GetSession(sessionID string) (*Session, error) {
+	if m.handler == nil {
+		return nil, ErrMemoryHandlerNotDefined
+	}
+	return m.handler.GetSession(sessionID)
+}
+
+func (m *Memory) DeleteSession(sessionID string) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.DeleteSession(sessionID)
+}
+
+func (m *Memory) RenameSession(sessionID, title string) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.RenameSession(sessionID, title)
+}
+
+func (m *Memory) ForkSession(sessionID, newSessionID string, n int) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.ForkSession(sessionID, newSessionID, n)
+}
+
+func (m *Memory) TruncateMessages(sessionID string, n int) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.TruncateMessages(sessionID, n)
+}
+
+func (m *Memory) ReplaceMessage(sessionID string, msg *message.Message) error {
+	if m.handler == nil {
+		return ErrMemoryHandlerNotDefined
+	}
+	return m.handler.ReplaceMessage(sessionID, msg)
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Session 会话信息
+type Session struct {
+	ID           string    `json:"id"`
+	Title        string    `json:"title,omitempty"` // 会话标题，可以通过 RenameSession 修改
+	CreatedAt    time.Time `json:"created_at"`      // 第一条消息的时间
+	UpdatedAt    time.Time `json:"updated_at"`      // 最后一条消息的时间
+	MessageCount int       `json:"message_count"`   // 消息数
+}
+
+// SortSessions 按最后更新时间倒序排列会话，时间相同则按ID排列
+func SortSessions(sessions []Session) {
+	slices.SortFunc(sessions, func(a, b Session) int {
+		if c := b.UpdatedAt.Compare(a.UpdatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// PageSessions 返回从offset开始的limit个会话，limit小于等于0则返回之后的全部
+func PageSessions(sessions []Session, offset, limit int) []Session {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(sessions) {
+		return []Session{}
+	}
+	sessions = sessions[offset:]
+	if limit > 0 && len(sessions) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions
+}
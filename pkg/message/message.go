@@ -1,8 +1,23 @@
 package message
 
+import "time"
+
 type Message struct {
-	Role       Role       `json:"role"`                   // 消息角色
-	Contents   []Content  `json:"content,omitempty"`      // 消息内容
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // 如果是assistant角色，可能有需要调用的工具列表
-	ToolCallID string     `json:"tool_call_id,omitempty"` // 如果是tool角色，需设定ToolCallID
+	ID          string     `json:"id,omitempty"`           // 消息ID，存入记忆时生成
+	CreatedAt   time.Time  `json:"created_at"`             // 创建时间，存入记忆时为空则使用当前时间
+	Role        Role       `json:"role"`                   // 消息角色
+	Name        string     `json:"name,omitempty"`         // 参与者名称，同一角色有多个参与者时用于区分
+	Contents    []Content  `json:"content,omitempty"`      // 消息内容
+	ToolCalls   []ToolCall `json:"tool_calls,omitempty"`   // 如果是assistant角色，可能有需要调用的工具列表
+	ToolCallID  string     `json:"tool_call_id,omitempty"` // 如果是tool角色，需设定ToolCallID
+	IsError     bool       `json:"is_error,omitempty"`     // 如果是tool角色，表示工具调用失败，内容为错误信息
+	Summary     bool       `json:"summary,omitempty"`      // 摘要消息，概括了之前的对话，替代被概括的消息传给思维
+	SummaryKeep int        `json:"summary_keep,omitempty"` // 摘要消息之前保留原文的消息数
+	Model       string     `json:"model,omitempty"`        // 如果是assistant角色，生成该消息的模型
+	Metadata    Metadata   `json:"metadata,omitempty"`     // 自定义数据，如评分、来源等
+
+	UpdateMeta map[string]any `json:"-"` // 如果是tool角色，工具可以通过它更新会话的meta，值为nil则删除对应的key，不会存入记忆
 }
+
+// Metadata 消息的自定义数据
+type Metadata map[string]any
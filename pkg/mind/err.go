@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrMindHandlerNotDefined = errors.New("mind handler is not defined")
+	ErrMindRouteNotFound     = errors.New("mind route not found")
 )
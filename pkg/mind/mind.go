@@ -1,14 +1,24 @@
 package mind
 
 import (
+	"context"
+
 	"github.com/deep-project/agent/pkg/ability"
 	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/schema"
+	"github.com/deep-project/agent/pkg/usage"
 )
 
 type Handler interface {
 	Call(opt *CallOptions) (*CallResponse, error)
 }
 
+// ContextHandler 支持context的handler，实现后可以通过context取消或超时中断调用
+type ContextHandler interface {
+	Handler
+	CallContext(ctx context.Context, opt *CallOptions) (*CallResponse, error)
+}
+
 type Mind struct {
 	handler Handler
 }
@@ -22,19 +32,47 @@ func (m *Mind) SetHandler(handler Handler) error {
 }
 
 func (m *Mind) Call(opt *CallOptions) (*CallResponse, error) {
+	return m.CallContext(context.Background(), opt)
+}
+
+func (m *Mind) CallContext(ctx context.Context, opt *CallOptions) (*CallResponse, error) {
 	if m.handler == nil {
 		return nil, ErrMindHandlerNotDefined
 	}
-	return m.handler.Call(opt)
+	return CallHandler(ctx, m.handler, opt)
+}
+
+// CallHandler 调用handler，如果handler不支持context，则在调用前检查context是否已取消
+func CallHandler(ctx context.Context, handler Handler, opt *CallOptions) (*CallResponse, error) {
+	if h, ok := handler.(ContextHandler); ok {
+		return h.CallContext(ctx, opt)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return handler.Call(opt)
 }
 
 type CallOptions struct {
-	Messages []message.Message
-	Tools    []Tool
+	Messages       []message.Message
+	Tools          []Tool
+	ResponseFormat *ResponseFormat // 要求按照json schema回复，为空则自由回复
+	Model          string          // 指定使用的模型，供路由选择思维，为空则由handler决定
+	Meta           ability.Meta    // 会话的meta，供路由等handler参考
+}
+
+// ResponseFormat 结构化输出的格式
+type ResponseFormat struct {
+	Name        string         // 格式名称，只能包含字母、数字、下划线和中划线
+	Description string         // 格式描述
+	Schema      *schema.Schema // 回复需要符合的json schema
+	Strict      bool           // 严格模式，要求schema中所有属性必填且不允许额外属性
 }
 
 type CallResponse struct {
 	Message message.Message
+	Model   string      // 实际使用的模型
+	Usage   usage.Usage // token用量
 }
 
 // Tool mind所需的tool结构需带唯一id
@@ -42,3 +80,69 @@ type Tool struct {
 	ID string
 	*ability.Tool
 }
+
+// StreamHandler 支持流式输出的handler
+// 调用过程中通过onDelta输出增量内容，结束后返回完整的回复
+type StreamHandler interface {
+	Handler
+	CallStream(ctx context.Context, opt *CallOptions, onDelta func(*StreamDelta) error) (*CallResponse, error)
+}
+
+// CallStream 流式调用思维，如果handler不支持流式输出，则在完整回复后一次性输出
+func (m *Mind) CallStream(ctx context.Context, opt *CallOptions, onDelta func(*StreamDelta) error) (*CallResponse, error) {
+	if m.handler == nil {
+		return nil, ErrMindHandlerNotDefined
+	}
+	return CallHandlerStream(ctx, m.handler, opt, onDelta)
+}
+
+// CallHandlerStream 流式调用handler，如果handler不支持流式输出，则在完整回复后一次性输出
+func CallHandlerStream(ctx context.Context, handler Handler, opt *CallOptions, onDelta func(*StreamDelta) error) (*CallResponse, error) {
+	if h, ok := handler.(StreamHandler); ok {
+		return h.CallStream(ctx, opt, onDelta)
+	}
+	resp, err := CallHandler(ctx, handler, opt)
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	for _, delta := range MessageToStreamDeltas(&resp.Message) {
+		if err = onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// MessageToStreamDeltas 将完整的消息拆分成增量内容
+func MessageToStreamDeltas(msg *message.Message) (res []*StreamDelta) {
+	for _, c := range msg.Contents {
+		if c.Type == message.ContentTypeText && c.Text.Text != "" {
+			res = append(res, &StreamDelta{Type: StreamDeltaText, Text: c.Text.Text})
+		}
+	}
+	for i, t := range msg.ToolCalls {
+		res = append(res,
+			&StreamDelta{Type: StreamDeltaToolCallStart, ToolCallIndex: i, ToolCallID: t.ID, ToolID: t.ToolID},
+			&StreamDelta{Type: StreamDeltaToolCallArguments, ToolCallIndex: i, ToolCallID: t.ID, ToolID: t.ToolID, Arguments: t.Arguments.String()},
+		)
+	}
+	return
+}
+
+type StreamDeltaType string
+
+const (
+	StreamDeltaText              StreamDeltaType = "text"                // 文本片段
+	StreamDeltaToolCallStart     StreamDeltaType = "tool_call_start"     // 开始一个工具调用
+	StreamDeltaToolCallArguments StreamDeltaType = "tool_call_arguments" // 工具调用参数片段
+)
+
+// StreamDelta 流式输出的增量内容
+type StreamDelta struct {
+	Type          StreamDeltaType
+	Text          string // 文本片段
+	ToolCallIndex int    // 工具调用在消息中的位置
+	ToolCallID    string // 工具调用ID
+	ToolID        string // 工具ID
+	Arguments     string // 工具调用参数片段，拼接后为完整的json
+}
@@ -0,0 +1,218 @@
+package mind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultRetryMaxRetries = 2                      // 默认重试次数
+	DefaultRetryBaseDelay  = 500 * time.Millisecond // 默认首次重试等待时间
+	DefaultRetryMaxDelay   = 30 * time.Second       // 默认最长等待时间
+)
+
+// StatusError 服务端返回的带状态码的错误
+// adapter应将接口错误包装成此类型，以便判断是否可以重试
+type StatusError struct {
+	StatusCode int           // http状态码
+	RetryAfter time.Duration // 服务端要求的重试等待时间，为0表示未指定
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("mind call failed, status code: %d", e.StatusCode)
+	}
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable 判断错误是否可以重试
+// 限流、超时、服务端错误和网络错误可以重试，请求错误和context取消不重试
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+			return true
+		}
+		return se.StatusCode >= http.StatusInternalServerError
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// RetryAfter 获取错误中服务端要求的重试等待时间
+func RetryAfter(err error) time.Duration {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.RetryAfter
+	}
+	return 0
+}
+
+// Retry 带重试和降级的handler
+// 可重试的错误会在等待后重试当前handler，重试用尽或遇到不可重试的错误时按顺序改用降级handler
+type Retry struct {
+	handlers   []Handler
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	retryable  func(error) bool
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+func NewRetry(handler Handler) *Retry {
+	return &Retry{
+		handlers:   []Handler{handler},
+		maxRetries: DefaultRetryMaxRetries,
+		baseDelay:  DefaultRetryBaseDelay,
+		maxDelay:   DefaultRetryMaxDelay,
+		retryable:  IsRetryable,
+		sleep:      sleepContext,
+	}
+}
+
+// SetMaxRetries 设置每个handler的最大重试次数，为0则不重试
+func (r *Retry) SetMaxRetries(n int) *Retry {
+	r.maxRetries = max(n, 0)
+	return r
+}
+
+// SetBackoff 设置退避时间，每次重试的等待时间翻倍并加入随机抖动，最长不超过maxDelay
+// 服务端要求的等待时间超过maxDelay时不再等待，直接改用降级handler
+func (r *Retry) SetBackoff(baseDelay, maxDelay time.Duration) *Retry {
+	r.baseDelay = baseDelay
+	r.maxDelay = maxDelay
+	return r
+}
+
+// SetRetryable 设置判断错误能否重试的方法，默认为IsRetryable
+func (r *Retry) SetRetryable(fn func(error) bool) *Retry {
+	if fn == nil {
+		fn = IsRetryable
+	}
+	r.retryable = fn
+	return r
+}
+
+// SetFallbacks 设置降级handler，主handler失败后按顺序尝试
+func (r *Retry) SetFallbacks(handlers ...Handler) *Retry {
+	r.handlers = append(r.handlers[:1:1], handlers...)
+	return r
+}
+
+func (r *Retry) Call(opt *CallOptions) (*CallResponse, error) {
+	return r.CallContext(context.Background(), opt)
+}
+
+func (r *Retry) CallContext(ctx context.Context, opt *CallOptions) (*CallResponse, error) {
+	return r.do(ctx, func(h Handler) (*CallResponse, bool, error) {
+		resp, err := CallHandler(ctx, h, opt)
+		return resp, true, err
+	})
+}
+
+// CallStream 流式调用，已经输出过增量内容的调用失败后不再重试，避免重复输出
+func (r *Retry) CallStream(ctx context.Context, opt *CallOptions, onDelta func(*StreamDelta) error) (*CallResponse, error) {
+	return r.do(ctx, func(h Handler) (*CallResponse, bool, error) {
+		emitted := false
+		resp, err := CallHandlerStream(ctx, h, opt, func(d *StreamDelta) error {
+			emitted = true
+			return onDelta(d)
+		})
+		return resp, !emitted, err
+	})
+}
+
+// Tokenizer 使用主handler的tokenizer
+func (r *Retry) Tokenizer() Tokenizer {
+	return handlerTokenizer(r.handlers[0])
+}
+
+// ContextWindow 取所有handler中最小的上下文窗口，保证降级后上下文仍然可用
+func (r *Retry) ContextWindow() int {
+	return handlerGroup(r.handlers).ContextWindow()
+}
+
+func (r *Retry) ReservedOutputTokens() int {
+	return handlerGroup(r.handlers).ReservedOutputTokens()
+}
+
+// do 依次尝试各个handler，call返回的bool表示失败后是否允许重试
+func (r *Retry) do(ctx context.Context, call func(Handler) (*CallResponse, bool, error)) (resp *CallResponse, err error) {
+	for _, h := range r.handlers {
+		for attempt := 0; ; attempt++ {
+			var retryable bool
+			resp, retryable, err = call(h)
+			if err == nil {
+				return resp, nil
+			}
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if !retryable {
+				return nil, err
+			}
+			if attempt >= r.maxRetries || !r.retryable(err) {
+				break
+			}
+			delay, ok := r.delay(attempt, err)
+			if !ok {
+				break
+			}
+			if e := r.sleep(ctx, delay); e != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, err
+}
+
+// delay 计算第attempt次重试前的等待时间，服务端要求的等待时间过长时返回false
+func (r *Retry) delay(attempt int, err error) (time.Duration, bool) {
+	if d := RetryAfter(err); d > 0 {
+		if r.maxDelay > 0 && d > r.maxDelay {
+			return 0, false
+		}
+		return d, true
+	}
+	d := r.baseDelay << attempt
+	if d <= 0 || (r.maxDelay > 0 && d > r.maxDelay) {
+		d = r.maxDelay
+	}
+	if d <= 0 {
+		return 0, true
+	}
+	// 在[d/2, d]之间随机，避免大量客户端同时重试
+	return d/2 + rand.N(d/2+1), true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
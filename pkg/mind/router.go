@@ -0,0 +1,162 @@
+package mind
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// RouteRule 路由规则，返回选中的思维名称，返回空字符串表示交给下一条规则判断
+type RouteRule func(ctx context.Context, opt *CallOptions) (string, error)
+
+// Router 根据规则在注册的多个思维之间选择，本身也是一个handler
+// 优先使用CallOptions.Model指定的思维，其次按添加顺序匹配规则，都未命中则使用默认思维
+type Router struct {
+	defaultName string
+	handlers    map[string]Handler
+	rules       []RouteRule
+}
+
+func NewRouter(defaultName string, defaultHandler Handler) *Router {
+	return &Router{
+		defaultName: defaultName,
+		handlers:    map[string]Handler{defaultName: defaultHandler},
+	}
+}
+
+// Register 注册思维
+func (r *Router) Register(name string, handler Handler) *Router {
+	r.handlers[name] = handler
+	return r
+}
+
+// AddRule 添加路由规则
+func (r *Router) AddRule(rules ...RouteRule) *Router {
+	r.rules = append(r.rules, rules...)
+	return r
+}
+
+// Route 选择本次调用使用的思维
+func (r *Router) Route(ctx context.Context, opt *CallOptions) (string, Handler, error) {
+	if opt.Model != "" {
+		return r.get(opt.Model)
+	}
+	for _, rule := range r.rules {
+		name, err := rule(ctx, opt)
+		if err != nil {
+			return "", nil, err
+		}
+		if name != "" {
+			return r.get(name)
+		}
+	}
+	return r.get(r.defaultName)
+}
+
+func (r *Router) get(name string) (string, Handler, error) {
+	h, ok := r.handlers[name]
+	if !ok || h == nil {
+		return "", nil, fmt.Errorf("%w: %s", ErrMindRouteNotFound, name)
+	}
+	return name, h, nil
+}
+
+func (r *Router) Call(opt *CallOptions) (*CallResponse, error) {
+	return r.CallContext(context.Background(), opt)
+}
+
+func (r *Router) CallContext(ctx context.Context, opt *CallOptions) (*CallResponse, error) {
+	name, h, err := r.Route(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := CallHandler(ctx, h, opt)
+	return routed(name, resp, err)
+}
+
+func (r *Router) CallStream(ctx context.Context, opt *CallOptions, onDelta func(*StreamDelta) error) (*CallResponse, error) {
+	name, h, err := r.Route(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := CallHandlerStream(ctx, h, opt, onDelta)
+	return routed(name, resp, err)
+}
+
+// routed 在回复中记录使用的模型，handler未返回模型名称时使用路由名称
+func routed(name string, resp *CallResponse, err error) (*CallResponse, error) {
+	if err != nil || resp == nil {
+		return resp, err
+	}
+	if resp.Model == "" {
+		resp.Model = name
+	}
+	return resp, nil
+}
+
+// Tokenizer 使用默认思维的tokenizer
+func (r *Router) Tokenizer() Tokenizer {
+	return handlerTokenizer(r.handlers[r.defaultName])
+}
+
+// ContextWindow 取所有思维中最小的上下文窗口，保证无论路由到哪个思维上下文都可用
+func (r *Router) ContextWindow() int {
+	return handlerGroup(slices.Collect(maps.Values(r.handlers))).ContextWindow()
+}
+
+func (r *Router) ReservedOutputTokens() int {
+	return handlerGroup(slices.Collect(maps.Values(r.handlers))).ReservedOutputTokens()
+}
+
+// RouteByMeta 使用会话meta中key对应的值作为思维名称
+func RouteByMeta(key string) RouteRule {
+	return func(ctx context.Context, opt *CallOptions) (string, error) {
+		name, _ := opt.Meta[key].(string)
+		return name, nil
+	}
+}
+
+// RouteByLength 消息文本总长度达到minChars时使用name
+func RouteByLength(minChars int, name string) RouteRule {
+	return func(ctx context.Context, opt *CallOptions) (string, error) {
+		n := 0
+		for _, msg := range opt.Messages {
+			for _, c := range msg.Contents {
+				if c.Type == message.ContentTypeText {
+					n += len([]rune(c.Text.Text))
+				}
+			}
+		}
+		if n >= minChars {
+			return name, nil
+		}
+		return "", nil
+	}
+}
+
+// RouteWithTools 提供了工具时使用name
+func RouteWithTools(name string) RouteRule {
+	return func(ctx context.Context, opt *CallOptions) (string, error) {
+		if len(opt.Tools) > 0 {
+			return name, nil
+		}
+		return "", nil
+	}
+}
+
+// RouteWithImages 消息中包含图片时使用name
+func RouteWithImages(name string) RouteRule {
+	return func(ctx context.Context, opt *CallOptions) (string, error) {
+		for _, msg := range opt.Messages {
+			for _, c := range msg.Contents {
+				if c.Type == message.ContentTypeImage {
+					return name, nil
+				}
+			}
+		}
+		return "", nil
+	}
+}
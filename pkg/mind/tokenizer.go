@@ -0,0 +1,133 @@
+package mind
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// Tokenizer 计算消息和工具占用的token数，用于裁切上下文，不需要完全精确
+type Tokenizer interface {
+	CountMessageTokens(msg *message.Message) int
+	CountToolTokens(tools []Tool) int
+}
+
+// TokenizerProvider handler可以实现此接口，提供与模型匹配的tokenizer
+type TokenizerProvider interface {
+	Tokenizer() Tokenizer
+}
+
+// ContextWindowProvider handler可以实现此接口，声明模型的上下文窗口
+type ContextWindowProvider interface {
+	ContextWindow() int        // 上下文窗口大小，为0表示未知
+	ReservedOutputTokens() int // 需要为输出预留的token数
+}
+
+// Tokenizer 获取handler的tokenizer，未实现 TokenizerProvider 则使用估算
+func (m *Mind) Tokenizer() Tokenizer {
+	if p, ok := m.handler.(TokenizerProvider); ok {
+		if t := p.Tokenizer(); t != nil {
+			return t
+		}
+	}
+	return EstimateTokenizer{}
+}
+
+// ContextBudget 获取可以用于输入的token数，为0表示不限制
+func (m *Mind) ContextBudget() int {
+	p, ok := m.handler.(ContextWindowProvider)
+	if !ok || p.ContextWindow() <= 0 {
+		return 0
+	}
+	return max(p.ContextWindow()-p.ReservedOutputTokens(), 1)
+}
+
+// handlerTokenizer 获取handler的tokenizer，未实现 TokenizerProvider 则返回nil
+func handlerTokenizer(h Handler) Tokenizer {
+	if p, ok := h.(TokenizerProvider); ok {
+		return p.Tokenizer()
+	}
+	return nil
+}
+
+// handlerGroup 组合了多个handler的思维（Retry、Router）共用的上下文窗口计算
+type handlerGroup []Handler
+
+// ContextWindow 取所有handler中最小的上下文窗口，未声明窗口的handler不参与计算
+func (g handlerGroup) ContextWindow() (res int) {
+	for _, h := range g {
+		p, ok := h.(ContextWindowProvider)
+		if !ok || p.ContextWindow() <= 0 {
+			continue
+		}
+		if res == 0 || p.ContextWindow() < res {
+			res = p.ContextWindow()
+		}
+	}
+	return
+}
+
+// ReservedOutputTokens 取所有handler中最多的预留输出token数
+func (g handlerGroup) ReservedOutputTokens() (res int) {
+	for _, h := range g {
+		if p, ok := h.(ContextWindowProvider); ok {
+			res = max(res, p.ReservedOutputTokens())
+		}
+	}
+	return
+}
+
+// EstimateTokenizer 离线估算token数
+// 英文等字符大约4个字符一个token，中日韩文字大约一个字一个token
+type EstimateTokenizer struct{}
+
+const (
+	estimateMessageOverhead = 4   // 每条消息的格式开销
+	estimateImageTokens     = 765 // 图片按高清图片的典型值估算
+)
+
+func (e EstimateTokenizer) CountMessageTokens(msg *message.Message) int {
+	n := estimateMessageOverhead + e.countText(string(msg.Role))
+	for _, c := range msg.Contents {
+		switch c.Type {
+		case message.ContentTypeText:
+			n += e.countText(c.Text.Text)
+		case message.ContentTypeImage:
+			n += estimateImageTokens
+		default:
+			n += e.countText(c.Resource.MIMEType) + len(c.Resource.Data)/4
+		}
+	}
+	for _, t := range msg.ToolCalls {
+		n += estimateMessageOverhead + e.countText(t.ID) + e.countText(t.ToolID) + e.countText(t.Arguments.String())
+	}
+	return n + e.countText(msg.ToolCallID)
+}
+
+func (e EstimateTokenizer) CountToolTokens(tools []Tool) (n int) {
+	for _, t := range tools {
+		n += estimateMessageOverhead + e.countText(t.ID) + e.countText(t.Description)
+		for _, p := range t.Parameters {
+			n += estimateMessageOverhead + e.countText(p.Name) + e.countText(p.Type) + e.countText(p.Description)
+			for _, v := range p.Enum {
+				n += e.countText(v)
+			}
+		}
+	}
+	return
+}
+
+func (e EstimateTokenizer) countText(s string) int {
+	var wide, other int
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			wide++
+		} else {
+			other++
+		}
+	}
+	return wide + (other+3)/4
+}
@@ -0,0 +1,193 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema json schema，只支持生成结构化输出所需的子集
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"-"` // 是否允许null，生成json时type为 [Type, "null"]
+}
+
+// MarshalJSON 实现 json.Marshaler，便于直接传给需要 json.Marshaler 的接口
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type alias Schema
+	if !s.Nullable || s.Type == "" {
+		return json.Marshal((*alias)(s))
+	}
+	return json.Marshal(struct {
+		Type []string `json:"type"`
+		*alias
+	}{[]string{s.Type, "null"}, (*alias)(s)})
+}
+
+// Strict 是否满足严格模式：所有对象的属性都是必填且不允许额外属性
+func (s *Schema) Strict() bool {
+	if s == nil {
+		return true
+	}
+	if s.Type == "object" {
+		if s.AdditionalProperties == nil || *s.AdditionalProperties || len(s.Required) != len(s.Properties) {
+			return false
+		}
+		for _, p := range s.Properties {
+			if !p.Strict() {
+				return false
+			}
+		}
+	}
+	if s.Type == "array" {
+		return s.Items.Strict()
+	}
+	return true
+}
+
+// For 根据Go类型生成schema
+// 字段名使用json标签，带有omitempty的字段为非必填，指针字段允许null
+// 匿名嵌入的结构体和 encoding/json 一样展开到外层
+// 可以使用 description 标签添加描述，使用 enum 标签（逗号分隔）限制取值
+func For[T any]() (*Schema, error) {
+	return Reflect(reflect.TypeFor[T]())
+}
+
+// Reflect 根据Go类型生成schema
+func Reflect(t reflect.Type) (*Schema, error) {
+	return reflectType(indirect(t), make(map[reflect.Type]bool))
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func reflectType(t reflect.Type, visiting map[reflect.Type]bool) (*Schema, error) {
+	if t.Kind() == reflect.Pointer {
+		res, err := reflectType(indirect(t), visiting)
+		if err != nil {
+			return nil, err
+		}
+		res.Nullable = res.Type != "" // encoding/json 把null解析为nil指针
+		return res, nil
+	}
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string"}, nil // []byte 在json中为base64字符串
+		}
+		items, err := reflectType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("schema: unsupported map key type %s", t.Key())
+		}
+		return &Schema{Type: "object"}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.Struct:
+		return reflectStruct(t, visiting)
+	}
+	return nil, fmt.Errorf("schema: unsupported type %s", t)
+}
+
+func reflectStruct(t reflect.Type, visiting map[reflect.Type]bool) (*Schema, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("schema: recursive type %s is not supported", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	additional := false
+	res := &Schema{Type: "object", Properties: make(map[string]*Schema), Required: []string{}, AdditionalProperties: &additional}
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, omitempty := "", false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			parts := strings.Split(tag, ",")
+			if parts[0] == "-" {
+				continue
+			}
+			name = parts[0]
+			for _, opt := range parts[1:] {
+				if opt == "omitempty" || opt == "omitzero" {
+					omitempty = true
+				}
+			}
+		}
+		// 没有json名称的匿名结构体字段展开到外层，未导出的结构体也会展开
+		if field.Anonymous && name == "" && indirect(field.Type).Kind() == reflect.Struct {
+			embedded = append(embedded, field)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		prop, err := reflectType(field.Type, visiting)
+		if err != nil {
+			return nil, err
+		}
+		prop.Description = field.Tag.Get("description")
+		if enum := field.Tag.Get("enum"); enum != "" {
+			for _, v := range strings.Split(enum, ",") {
+				prop.Enum = append(prop.Enum, v)
+			}
+		}
+		res.Properties[name] = prop
+		if !omitempty {
+			res.Required = append(res.Required, name)
+		}
+	}
+	// 外层的同名字段优先；嵌入的指针为nil时其字段不会输出，所以为非必填
+	for _, field := range embedded {
+		inner, err := reflectStruct(indirect(field.Type), visiting)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range slices.Sorted(maps.Keys(inner.Properties)) {
+			if _, ok := res.Properties[name]; ok {
+				continue
+			}
+			res.Properties[name] = inner.Properties[name]
+			if field.Type.Kind() != reflect.Pointer && slices.Contains(inner.Required, name) {
+				res.Required = append(res.Required, name)
+			}
+		}
+	}
+	return res, nil
+}
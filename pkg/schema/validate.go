@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// ValidationError 数据不符合schema
+type ValidationError struct {
+	Path    string // 出错的位置，例如 $.items[0].name
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Validate 校验json数据是否符合schema
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Path: "$", Message: "invalid json: " + err.Error()}
+	}
+	if decoder.More() {
+		return &ValidationError{Path: "$", Message: "unexpected data after json value"}
+	}
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value any) error {
+	if s == nil || (s.Nullable && value == nil) {
+		return nil
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value %v is not one of %v", value, s.Enum)}
+	}
+	switch s.Type {
+	case "":
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return s.typeError(path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return s.typeError(path, value)
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return s.typeError(path, value)
+		}
+		if _, err := n.Int64(); err != nil {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("expected integer, got %s", n)}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return s.typeError(path, value)
+		}
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			return s.typeError(path, value)
+		}
+		for i, item := range list {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return s.typeError(path, value)
+		}
+		for _, name := range s.Required {
+			if _, ok := obj[name]; !ok {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+		for name, v := range obj {
+			prop, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("unexpected property %q", name)}
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+name, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) typeError(path string, value any) error {
+	got := "null"
+	switch value.(type) {
+	case string:
+		got = "string"
+	case bool:
+		got = "boolean"
+	case json.Number:
+		got = "number"
+	case []any:
+		got = "array"
+	case map[string]any:
+		got = "object"
+	}
+	return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", s.Type, got)}
+}
@@ -0,0 +1,68 @@
+package usage
+
+import "strings"
+
+// Usage token用量和费用
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`     // 输入token数，包括命中缓存的token
+	CompletionTokens int     `json:"completion_tokens"` // 输出token数，包括推理token
+	CachedTokens     int     `json:"cached_tokens"`     // 输入中命中缓存的token数
+	ReasoningTokens  int     `json:"reasoning_tokens"`  // 输出中推理的token数
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"` // 费用，根据价格表计算
+}
+
+// Add 累加用量
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.CachedTokens += o.CachedTokens
+	u.ReasoningTokens += o.ReasoningTokens
+	u.TotalTokens += o.TotalTokens
+	u.Cost += o.Cost
+}
+
+// IsZero 是否没有任何用量
+func (u Usage) IsZero() bool {
+	return u == Usage{}
+}
+
+// Price 模型价格，单位为每百万token的价格
+type Price struct {
+	Prompt     float64 `json:"prompt"`     // 输入
+	Completion float64 `json:"completion"` // 输出
+	Cached     float64 `json:"cached"`     // 命中缓存的输入，为0则按输入价格计算
+}
+
+// Pricing 价格表，key为模型名称
+type Pricing map[string]Price
+
+// Cost 计算费用
+// 先按模型名称精确匹配，找不到则使用最长的前缀匹配，例如 gpt-4o-2024-08-06 可以匹配 gpt-4o
+func (p Pricing) Cost(model string, u Usage) float64 {
+	price, ok := p.find(model)
+	if !ok {
+		return 0
+	}
+	cached := price.Cached
+	if cached == 0 {
+		cached = price.Prompt
+	}
+	return (float64(u.PromptTokens-u.CachedTokens)*price.Prompt +
+		float64(u.CachedTokens)*cached +
+		float64(u.CompletionTokens)*price.Completion) / 1e6
+}
+
+func (p Pricing) find(model string) (Price, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	var match string
+	for name := range p {
+		if strings.HasPrefix(model, name) && len(name) > len(match) {
+			match = name
+		}
+	}
+	price, ok := p[match]
+	return price, ok && match != ""
+}
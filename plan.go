@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/deep-project/agent/pkg/ability"
+
+	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/schema"
+)
+
+const (
+	DefaultPlanMaxSteps     = 10     // 计划默认最多的步骤数
+	DefaultPlanMaxRevisions = 2      // 步骤失败后默认修改计划的次数
+	PlanMetaKey             = "plan" // 会话meta中保存计划的key
+	planPrompt              = "Before doing anything, make a plan for the user's latest request. " +
+		"Break it into at most %d concrete steps that can each be completed with the available tools. " +
+		"Do not execute anything yet. Reply with the plan only."
+	planStepPrompt = "You are executing the plan below step by step.\n%s\n" +
+		"Now execute step %d: %s\nUse tools as needed. When the step is done, reply with its status and result; " +
+		"use status \"failed\" if the step cannot be completed."
+	planRevisePrompt = "Step %d of the plan below failed: %s\n%s\n" +
+		"Revise the remaining steps so the user's request can still be completed. " +
+		"Reply with the new remaining steps only, at most %d steps."
+	planSynthesisPrompt = "All steps of the plan below have been executed.\n%s\n" +
+		"Using the step results, give the user the final answer to their request."
+)
+
+type PlanStepStatus string
+
+const (
+	PlanStepPending   PlanStepStatus = "pending"   // 等待执行
+	PlanStepRunning   PlanStepStatus = "running"   // 正在执行
+	PlanStepCompleted PlanStepStatus = "completed" // 执行完成
+	PlanStepFailed    PlanStepStatus = "failed"    // 执行失败
+	PlanStepSkipped   PlanStepStatus = "skipped"   // 修改计划后不再执行
+)
+
+// PlanStep 计划的一个步骤
+type PlanStep struct {
+	Description string         `json:"description"`
+	Status      PlanStepStatus `json:"status"`
+	Result      string         `json:"result,omitempty"` // 执行结果或失败原因
+}
+
+// Plan 计划执行模式下，思维为本轮交互制定的计划
+type Plan struct {
+	Steps     []PlanStep `json:"steps"`
+	Revisions int        `json:"revisions"` // 已修改计划的次数
+}
+
+// String 计划的文本描述，用于提示思维
+func (p *Plan) String() string {
+	var b strings.Builder
+	b.WriteString("Plan:")
+	for i, step := range p.Steps {
+		fmt.Fprintf(&b, "\n%d. [%s] %s", i+1, step.Status, step.Description)
+		if step.Result != "" {
+			fmt.Fprintf(&b, "\n   Result: %s", step.Result)
+		}
+	}
+	return b.String()
+}
+
+// runningStep 正在执行的步骤位置，没有则返回-1
+func (p *Plan) runningStep() int {
+	for i, step := range p.Steps {
+		if step.Status == PlanStepRunning {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Plan) clone() *Plan {
+	res := *p
+	res.Steps = append([]PlanStep(nil), p.Steps...)
+	return &res
+}
+
+// PlanOptions 计划执行模式设置
+type PlanOptions struct {
+	MaxSteps     int                                         // 计划最多的步骤数，为0则使用 DefaultPlanMaxSteps
+	MaxRevisions int                                         // 步骤失败后修改计划的次数，为0则使用 DefaultPlanMaxRevisions，小于0则不修改
+	OnPlan       func(ctx context.Context, plan *Plan) error // 计划制定或修改后、执行前调用，返回错误则中断交互
+}
+
+// SetPlanning 开启计划执行模式，为空则关闭
+// 开启后思维先制定计划，然后逐步使用工具执行，步骤失败时修改计划，最后综合各步骤的结果回复
+func (a *Agent) SetPlanning(options *PlanOptions) *Agent {
+	a.planning = options
+	return a
+}
+
+// GetPlan 获取会话最近一次交互的计划，返回的是副本，修改不影响保存的计划
+func (a *Agent) GetPlan(sessionID string) (*Plan, error) {
+	meta, err := a.memory.GetMeta(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := meta[PlanMetaKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	if plan, ok := v.(*Plan); ok {
+		return plan.clone(), nil
+	}
+	// 持久化的记忆中保存的是json解析后的结构
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	plan := new(Plan)
+	return plan, json.Unmarshal(data, plan)
+}
+
+type planResponse struct {
+	Steps []struct {
+		Description string `json:"description" description:"what to do in this step"`
+	} `json:"steps"`
+}
+
+type planStepResponse struct {
+	Status string `json:"status" enum:"completed,failed"`
+	Result string `json:"result" description:"the result of the step, or why it failed"`
+}
+
+var (
+	planFormat     = mustResponseFormat[planResponse]("plan")
+	planStepFormat = mustResponseFormat[planStepResponse]("plan_step")
+)
+
+func mustResponseFormat[T any](name string) *mind.ResponseFormat {
+	s, err := schema.For[T]()
+	if err != nil {
+		panic(err)
+	}
+	return &mind.ResponseFormat{Name: name, Schema: s, Strict: s.Strict()}
+}
+
+// planLimits 计划最多的步骤数和修改次数
+func (a *Agent) planLimits() (maxSteps, maxRevisions int) {
+	maxSteps = a.planning.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultPlanMaxSteps
+	}
+	maxRevisions = a.planning.MaxRevisions
+	if maxRevisions == 0 {
+		maxRevisions = DefaultPlanMaxRevisions
+	}
+	return
+}
+
+// callPlan 以计划执行模式完成本轮交互
+func (a *Agent) callPlan(ctx context.Context, t *turn) (*InteractOutput, error) {
+	maxSteps, _ := a.planLimits()
+	steps, err := a.makePlan(ctx, t, fmt.Sprintf(planPrompt, maxSteps), maxSteps)
+	if err != nil {
+		return t.output, err
+	}
+	plan := &Plan{Steps: steps}
+	if err = a.updatePlan(ctx, t, plan, true); err != nil {
+		return t.output, err
+	}
+	return a.executePlan(ctx, t, plan, 0)
+}
+
+// executePlan 从第start个步骤开始执行计划，最后综合各步骤的结果回复
+// 步骤中的工具调用等待人工确认时暂停，Resume 后从正在执行的步骤继续
+func (a *Agent) executePlan(ctx context.Context, t *turn, plan *Plan, start int) (*InteractOutput, error) {
+	maxSteps, maxRevisions := a.planLimits()
+	for i := start; i < len(plan.Steps); i++ {
+		step := &plan.Steps[i]
+		if step.Status != PlanStepRunning {
+			step.Status = PlanStepRunning
+			if err := a.updatePlan(ctx, t, plan, false); err != nil {
+				return t.output, err
+			}
+		}
+		t.prompt, t.format = fmt.Sprintf(planStepPrompt, plan, i+1, step.Description), planStepFormat
+		t.responseRetries = 0
+		output, err := a.call(ctx, t)
+		t.prompt, t.format = "", nil
+		if err != nil || output.Status != InteractStatusCompleted {
+			return output, err
+		}
+		var res planStepResponse
+		if err = json.Unmarshal([]byte(ResponseJSON(&output.Message)), &res); err != nil {
+			return output, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+		}
+		step.Result = res.Result
+		step.Status = PlanStepCompleted
+		if res.Status == string(PlanStepFailed) {
+			step.Status = PlanStepFailed
+		}
+		if step.Status == PlanStepCompleted || maxRevisions < 0 || plan.Revisions >= maxRevisions {
+			if err := a.updatePlan(ctx, t, plan, false); err != nil {
+				return t.output, err
+			}
+			continue
+		}
+		// 步骤失败，重新制定剩余的步骤
+		steps, err := a.makePlan(ctx, t, fmt.Sprintf(planRevisePrompt, i+1, step.Result, plan, maxSteps), maxSteps)
+		if err != nil {
+			return t.output, err
+		}
+		for j := i + 1; j < len(plan.Steps); j++ {
+			plan.Steps[j].Status = PlanStepSkipped
+		}
+		plan.Steps = append(plan.Steps, steps...)
+		plan.Revisions++
+		if err := a.updatePlan(ctx, t, plan, true); err != nil {
+			return t.output, err
+		}
+		// 跳过被替换的步骤
+		for i+1 < len(plan.Steps) && plan.Steps[i+1].Status == PlanStepSkipped {
+			i++
+		}
+	}
+
+	t.prompt = fmt.Sprintf(planSynthesisPrompt, plan)
+	defer func() { t.prompt = "" }()
+	if err := a.callResponse(ctx, t); err != nil {
+		return t.output, err
+	}
+	t.output.Status = InteractStatusCompleted
+	return t.output, nil
+}
+
+// makePlan 要求思维按照prompt制定步骤
+func (a *Agent) makePlan(ctx context.Context, t *turn, prompt string, maxSteps int) ([]PlanStep, error) {
+	t.prompt, t.format = prompt, planFormat
+	defer func() { t.prompt, t.format = "", nil }()
+	if err := a.callResponse(ctx, t); err != nil {
+		return nil, err
+	}
+	var res planResponse
+	if err := json.Unmarshal([]byte(ResponseJSON(&t.output.Message)), &res); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	var steps []PlanStep
+	for _, s := range res.Steps {
+		if len(steps) < maxSteps && s.Description != "" {
+			steps = append(steps, PlanStep{Description: s.Description, Status: PlanStepPending})
+		}
+	}
+	return steps, nil
+}
+
+// callResponse 不使用工具调用思维，回复不符合要求的格式时重新要求回复
+func (a *Agent) callResponse(ctx context.Context, t *turn) error {
+	t.responseRetries = 0
+	for {
+		resp, err := a.callMind(ctx, t, false)
+		if err != nil {
+			return err
+		}
+		if err = a.validateResponse(t, &resp.Message); err == nil {
+			return nil
+		}
+		if err = a.retryResponse(t, err); err != nil {
+			return err
+		}
+	}
+}
+
+// updatePlan 保存计划到会话meta并输出事件，changed表示计划内容有变化，需要调用OnPlan
+func (a *Agent) updatePlan(ctx context.Context, t *turn, plan *Plan, changed bool) error {
+	if changed && a.planning.OnPlan != nil {
+		if err := a.planning.OnPlan(ctx, plan); err != nil {
+			return err
+		}
+	}
+	// 保存和输出快照，避免之后修改步骤状态时影响已经输出的计划
+	snapshot := plan.clone()
+	if err := a.memory.UpdateMeta(t.input.SessionID, ability.Meta{PlanMetaKey: snapshot}); err != nil {
+		return err
+	}
+	t.output.Plan = snapshot
+	t.send(StreamEvent{Type: StreamEventPlan, Plan: snapshot})
+	return nil
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+// Regenerate 丢弃最后一条用户消息之后的全部消息（包括工具调用和工具结果），重新生成回复
+func (a *Agent) Regenerate(sessionID string) (*InteractOutput, error) {
+	return a.RegenerateContext(context.Background(), &InteractInput{SessionID: sessionID, MessagesLimit: 50})
+}
+
+// RegenerateContext 重新生成回复，input不能包含消息
+func (a *Agent) RegenerateContext(ctx context.Context, input *InteractInput) (*InteractOutput, error) {
+	return a.rewind(ctx, input, func(messages []message.Message) (int, *message.Message, error) {
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == message.RoleUser {
+				return i + 1, nil, nil
+			}
+		}
+		return 0, nil, ErrNoMessageToRegenerate
+	})
+}
+
+// EditMessage 修改指定的用户消息，丢弃其后的全部消息并重新生成回复
+func (a *Agent) EditMessage(sessionID, messageID string, contents []message.Content) (*InteractOutput, error) {
+	return a.EditMessageContext(context.Background(), &InteractInput{SessionID: sessionID, MessagesLimit: 50}, messageID, contents)
+}
+
+// EditMessageContext 修改指定的用户消息并重新生成回复，input不能包含消息
+func (a *Agent) EditMessageContext(ctx context.Context, input *InteractInput, messageID string, contents []message.Content) (*InteractOutput, error) {
+	return a.rewind(ctx, input, func(messages []message.Message) (int, *message.Message, error) {
+		i := slices.IndexFunc(messages, func(msg message.Message) bool { return messageID != "" && msg.ID == messageID })
+		if i < 0 {
+			return 0, nil, memory.ErrMessageNotFound
+		}
+		if messages[i].Role != message.RoleUser {
+			return 0, nil, ErrMessageNotEditable
+		}
+		msg := messages[i]
+		msg.Contents = contents
+		msg.CreatedAt = time.Now()
+		return i + 1, &msg, nil
+	})
+}
+
+// rewind 在会话锁内截断历史消息后重新执行思维循环，失败时恢复原有的历史
+// keep 返回需要保留的消息数量，以及替换最后一条保留消息的修改后消息
+func (a *Agent) rewind(ctx context.Context, input *InteractInput, keep func(messages []message.Message) (int, *message.Message, error)) (output *InteractOutput, err error) {
+	if input == nil || input.SessionID == "" {
+		return nil, errors.New("rewind session id is empty")
+	}
+	if len(input.Messages) > 0 {
+		return nil, errors.New("rewind input cannot contain messages")
+	}
+	unlock, err := a.sessions.Lock(ctx, input.SessionID)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	t := newTurn(input)
+	if err = a.mergeInputMeta(t); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	messages, err := a.ListMessages(input.SessionID, 0)
+	if err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	n, edited, err := keep(messages)
+	if err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	defer func() {
+		if err != nil {
+			err = a.restoreMessages(input.SessionID, messages, n, edited != nil, err)
+		}
+	}()
+	if edited != nil {
+		if err = a.memory.ReplaceMessage(input.SessionID, edited); err != nil {
+			return nil, a.handleError(ctx, err)
+		}
+	}
+	if err = a.memory.TruncateMessages(input.SessionID, n); err != nil {
+		return nil, a.handleError(ctx, err)
+	}
+	if output, err = a.run(ctx, t); err != nil {
+		return output, a.handleError(ctx, err)
+	}
+	return
+}
+
+// restoreMessages 重新执行失败时恢复原有的历史：删除新写入的消息，还原被修改的消息，重新写入被丢弃的消息
+// 保留的消息因为超出最大数量被挤出时，清空后重新写入全部原有的消息
+func (a *Agent) restoreMessages(sessionID string, messages []message.Message, n int, edited bool, err error) error {
+	cp := &memory.Checkpoint{SessionID: sessionID, LastID: messages[n-1].ID, Count: n, Exists: true}
+	if e := a.memory.Rollback(cp); e != nil {
+		return errors.Join(err, e)
+	}
+	s, e := a.memory.GetSession(sessionID)
+	if e != nil {
+		return errors.Join(err, e)
+	}
+	if s.MessageCount != n {
+		if e = a.memory.TruncateMessages(sessionID, 0); e != nil {
+			return errors.Join(err, e)
+		}
+		n, edited = 0, false
+	}
+	if edited {
+		if e = a.memory.ReplaceMessage(sessionID, &messages[n-1]); e != nil {
+			return errors.Join(err, e)
+		}
+	}
+	if e = a.memory.AddMessages(sessionID, slices.Clone(messages[n:])); e != nil {
+		return errors.Join(err, e)
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+
+	"github.com/google/uuid"
+)
+
+// sessionLocker 会话锁
+// 同一会话的交互需要依次执行以保证消息顺序，不同会话之间互不影响
+type sessionLocker struct {
+	locks map[string]*sessionLock
+	mu    sync.Mutex
+}
+
+type sessionLock struct {
+	ch   chan struct{}
+	refs int // 持有或等待该锁的数量，为0时释放
+}
+
+// Lock 锁定会话，ctx取消时放弃等待
+func (l *sessionLocker) Lock(ctx context.Context, sessionID string) (unlock func(), err error) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sessionLock)
+	}
+	lock, ok := l.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{ch: make(chan struct{}, 1)}
+		l.locks[sessionID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	select {
+	case lock.ch <- struct{}{}:
+		return func() {
+			<-lock.ch
+			l.release(sessionID, lock)
+		}, nil
+	case <-ctx.Done():
+		l.release(sessionID, lock)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *sessionLocker) release(sessionID string, lock *sessionLock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, sessionID)
+	}
+}
+
+// ListSessions 按最后更新时间倒序列出会话，返回当前页和会话总数，limit小于等于0则返回全部
+func (a *Agent) ListSessions(offset, limit int) ([]memory.Session, int, error) {
+	return a.memory.ListSessions(offset, limit)
+}
+
+// GetSession 获取会话信息
+func (a *Agent) GetSession(sessionID string) (*memory.Session, error) {
+	return a.memory.GetSession(sessionID)
+}
+
+// DeleteSession 删除会话的消息、meta和用量，以及委派产生的子会话，会等待正在进行的交互结束
+func (a *Agent) DeleteSession(sessionID string) error {
+	unlock, err := a.sessions.Lock(context.Background(), sessionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	subs, err := a.subSessions(sessionID)
+	if err != nil {
+		return err
+	}
+	for _, s := range subs {
+		if err = s.memory.DeleteSession(s.id); err != nil {
+			return err
+		}
+	}
+	return a.memory.DeleteSession(sessionID)
+}
+
+type subSession struct {
+	memory *memory.Memory
+	id     string
+}
+
+// subSessions 查找会话委派产生的各级子会话
+// 子agent可能使用不同的记忆，需要在委派能力的agent中逐级查找
+func (a *Agent) subSessions(sessionID string) (res []subSession, err error) {
+	prefix := SubSessionID(sessionID, "")
+	visited := make(map[*Agent]bool)
+	found := make(map[string]bool)
+	var walk func(agent *Agent) error
+	walk = func(agent *Agent) error {
+		visited[agent] = true
+		sessions, _, err := agent.memory.ListSessions(0, 0)
+		if err != nil && !errors.Is(err, memory.ErrMemoryHandlerNotDefined) {
+			return err
+		}
+		for _, s := range sessions {
+			if strings.HasPrefix(s.ID, prefix) && !found[s.ID] {
+				found[s.ID] = true
+				res = append(res, subSession{agent.memory, s.ID})
+			}
+		}
+		for _, item := range agent.ability.Items() {
+			if d, ok := item.Handler().(*AgentAbility); ok && !visited[d.agent] {
+				if err = walk(d.agent); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = walk(a)
+	return
+}
+
+// RenameSession 修改会话标题
+func (a *Agent) RenameSession(sessionID, title string) error {
+	return a.memory.RenameSession(sessionID, title)
+}
+
+// ForkSession 以会话的前n条消息创建新会话，返回新会话ID，n小于等于0则复制全部消息
+func (a *Agent) ForkSession(sessionID string, n int) (string, error) {
+	unlock, err := a.sessions.Lock(context.Background(), sessionID)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	newSessionID := uuid.New().String()
+	return newSessionID, a.memory.ForkSession(sessionID, newSessionID, n)
+}
+
+// SessionExport 导出的会话
+type SessionExport struct {
+	Session  memory.Session    `json:"session"`
+	Messages []message.Message `json:"messages"`
+	Meta     ability.Meta      `json:"meta"`
+	Usage    usage.Usage       `json:"usage"`
+
+	SubSessions []SessionExport `json:"sub_sessions,omitempty"` // 委派产生的各级子会话
+}
+
+// ExportSession 导出会话的信息、全部消息、meta和用量，以及委派产生的子会话
+func (a *Agent) ExportSession(sessionID string) (*SessionExport, error) {
+	unlock, err := a.sessions.Lock(context.Background(), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	res, err := exportSession(a.memory, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	subs, err := a.subSessions(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range subs {
+		sub, err := exportSession(s.memory, s.id)
+		if err != nil {
+			return nil, err
+		}
+		res.SubSessions = append(res.SubSessions, *sub)
+	}
+	return res, nil
+}
+
+func exportSession(m *memory.Memory, sessionID string) (*SessionExport, error) {
+	s, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	res := &SessionExport{Session: *s}
+	if res.Messages, err = m.ListMessages(sessionID, 0); err != nil {
+		return nil, err
+	}
+	if res.Meta, err = m.GetMeta(sessionID); err != nil {
+		return nil, err
+	}
+	if res.Usage, err = m.GetUsage(sessionID); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
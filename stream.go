@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/deep-project/agent/pkg/message"
+
+	"github.com/google/uuid"
+)
+
+type StreamEventType string
+
+const (
+	StreamEventTextDelta         StreamEventType = "text_delta"          // 文本片段
+	StreamEventToolCallStart     StreamEventType = "tool_call_start"     // 开始生成工具调用
+	StreamEventToolCallArguments StreamEventType = "tool_call_arguments" // 工具调用参数片段
+	StreamEventToolCallFinish    StreamEventType = "tool_call_finish"    // 工具调用生成完毕，携带完整参数
+	StreamEventToolResult        StreamEventType = "tool_result"         // 工具调用结果
+	StreamEventMessage           StreamEventType = "message"             // 思维回复的完整消息
+	StreamEventPlan              StreamEventType = "plan"                // 计划制定或步骤状态变化，携带完整计划
+	StreamEventDone              StreamEventType = "done"                // 交互结束，携带最终结果
+	StreamEventError             StreamEventType = "error"               // 交互出错，携带错误和部分结果
+)
+
+// StreamEvent 流式交互事件
+type StreamEvent struct {
+	Type      StreamEventType   `json:"type"`
+	SessionID string            `json:"session_id"`
+	Text      string            `json:"text,omitempty"`      // 文本片段
+	Index     int               `json:"index"`               // 工具调用在消息中的位置
+	ToolCall  *message.ToolCall `json:"tool_call,omitempty"` // 工具调用
+	Arguments string            `json:"arguments,omitempty"` // 工具调用参数片段
+	Message   *message.Message  `json:"message,omitempty"`   // 完整消息或工具调用结果
+	Output    *InteractOutput   `json:"output,omitempty"`    // 交互结果
+	Plan      *Plan             `json:"plan,omitempty"`      // 计划
+	Err       error             `json:"-"`
+
+	ParentToolCallID string `json:"parent_tool_call_id,omitempty"` // 委派给子agent时，上级会话中的工具调用ID
+}
+
+// InteractStream 以流式方式与agent交互
+// 返回的事件通道在交互结束后关闭，最后一个事件为 StreamEventDone 或 StreamEventError
+// ctx取消后交互中断，不再发送事件
+func (a *Agent) InteractStream(ctx context.Context, input *InteractInput) (<-chan StreamEvent, error) {
+	if input == nil {
+		return nil, errors.New("interact input is empty")
+	}
+	if input.SessionID == "" {
+		input.SessionID = uuid.New().String()
+	}
+	events := make(chan StreamEvent, 64)
+	go func() {
+		defer close(events)
+		emit := func(event StreamEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+		t := newTurn(input)
+		t.emit = emit
+		output, err := a.interact(ctx, t)
+		if err != nil {
+			t.send(StreamEvent{Type: StreamEventError, Output: output, Err: err})
+			return
+		}
+		t.send(StreamEvent{Type: StreamEventDone, Output: output, Message: &output.Message})
+	}()
+	return events, nil
+}
@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/deep-project/agent/internal/helpers"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/schema"
+)
+
+// 默认回复不符合格式时重新要求回复的次数
+const DefaultMaxResponseRetries = 2
+
+var responseFormatNameRegexp = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// InteractTyped 要求思维按照T的结构回复，校验后将回复解析到T
+// T的schema根据字段的json标签生成，见 schema.For
+// 等待人工确认工具调用时返回的T为nil
+func InteractTyped[T any](ctx context.Context, a *Agent, input *InteractInput) (*T, *InteractOutput, error) {
+	if input == nil {
+		return nil, nil, errors.New("interact input is empty")
+	}
+	s, err := schema.For[T]()
+	if err != nil {
+		return nil, nil, err
+	}
+	name := responseFormatNameRegexp.ReplaceAllString(reflect.TypeFor[T]().Name(), "_")
+	if name == "" {
+		name = "response"
+	}
+	// 复制input，避免调用方复用input时保留本次的回复格式
+	in := *input
+	in.ResponseFormat = &mind.ResponseFormat{Name: name, Schema: s, Strict: s.Strict()}
+	output, err := a.InteractContext(ctx, &in)
+	if err != nil || output.Status != InteractStatusCompleted {
+		return nil, output, err
+	}
+	var res T
+	if err = json.Unmarshal([]byte(ResponseJSON(&output.Message)), &res); err != nil {
+		return nil, output, fmt.Errorf("%w: %w", ErrInvalidResponse, err)
+	}
+	return &res, output, nil
+}
+
+// ResponseJSON 提取回复中的json文本，会去掉模型有时添加的markdown代码块
+func ResponseJSON(msg *message.Message) string {
+	return helpers.TrimCodeFence(helpers.JoinTextMessageContents(msg.Contents))
+}
+
+// validateResponse 校验回复是否符合本轮交互要求的格式
+func (a *Agent) validateResponse(t *turn, msg *message.Message) error {
+	f := t.responseFormat()
+	if f == nil || f.Schema == nil {
+		return nil
+	}
+	return f.Schema.Validate([]byte(ResponseJSON(msg)))
+}
+
+// retryResponse 回复不符合格式时，将错误反馈给思维重新回复，超过重试次数则返回错误
+// 不符合格式的回复和纠正提示不存入记忆，只在本轮交互之后的思维调用中作为上下文
+func (a *Agent) retryResponse(t *turn, validateErr error) error {
+	retries := t.input.MaxResponseRetries
+	if retries == 0 {
+		retries = DefaultMaxResponseRetries
+	}
+	if t.responseRetries >= retries {
+		return fmt.Errorf("%w: %w", ErrInvalidResponse, validateErr)
+	}
+	t.responseRetries++
+	msg := message.Message{
+		Role: message.RoleUser,
+		Contents: []message.Content{message.NewMessageWithContentText(
+			fmt.Sprintf("Your reply does not match the required JSON schema (%s). Reply again with valid JSON only.", validateErr.Error()),
+		)},
+	}
+	t.retryMessages = append(t.retryMessages, t.output.Message, msg)
+	return nil
+}
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultMaxHandoffs  = 5            // 每轮交互默认允许转交的次数
+	TeamActiveAgentMeta = "team_agent" // 会话meta中记录当前agent名称的key
+)
+
+// TeamMember 团队成员
+type TeamMember struct {
+	Name        string   // 名称，转交工具名为 transfer_to_<Name>
+	Description string   // 描述，其他成员根据描述决定是否转交
+	Agent       *Agent   // 成员agent，记忆会被替换为团队的记忆
+	Handoffs    []string // 可以转交的成员名称，为空则可以转交给所有其他成员
+}
+
+// Team 多个agent共用一个会话，可以互相转交对话
+// 第一个成员为默认成员，转交后由目标成员负责该会话之后的交互，当前成员记录在会话meta中
+type Team struct {
+	members     []TeamMember
+	memory      *memory.Memory
+	sessions    sessionLocker
+	maxHandoffs int
+}
+
+// NewTeam 创建团队，所有成员使用同一个记忆，并被赋予转交给其他成员的工具
+func NewTeam(handler memory.Handler, members ...TeamMember) (*Team, error) {
+	if len(members) == 0 {
+		return nil, errors.New("team members cannot be empty")
+	}
+	tm := &Team{members: members, memory: new(memory.Memory), maxHandoffs: DefaultMaxHandoffs}
+	if err := tm.memory.SetHandler(handler); err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.Agent == nil {
+			return nil, fmt.Errorf("team member %s has no agent", member.Name)
+		}
+		for _, name := range member.Handoffs {
+			if tm.member(name) == nil {
+				return nil, fmt.Errorf("%w: %s", ErrTeamAgentNotFound, name)
+			}
+		}
+		member.Agent.GrantMemory(handler).GrantAbility(&handoffAbility{team: tm, owner: member.Name})
+	}
+	return tm, nil
+}
+
+// SetMaxHandoffs 设置每轮交互允许转交的次数，超过后返回 ErrMaxHandoffsExceeded
+func (tm *Team) SetMaxHandoffs(n int) *Team {
+	tm.maxHandoffs = n
+	return tm
+}
+
+func (tm *Team) member(name string) *TeamMember {
+	for i := range tm.members {
+		if tm.members[i].Name == name {
+			return &tm.members[i]
+		}
+	}
+	return nil
+}
+
+// ActiveAgent 获取会话当前的成员名称
+func (tm *Team) ActiveAgent(sessionID string) (string, error) {
+	meta, err := tm.memory.GetMeta(sessionID)
+	if err != nil {
+		return "", err
+	}
+	if name, _ := meta[TeamActiveAgentMeta].(string); tm.member(name) != nil {
+		return name, nil
+	}
+	return tm.members[0].Name, nil
+}
+
+// SetActiveAgent 指定会话当前的成员
+func (tm *Team) SetActiveAgent(sessionID, name string) error {
+	if tm.member(name) == nil {
+		return fmt.Errorf("%w: %s", ErrTeamAgentNotFound, name)
+	}
+	return tm.memory.UpdateMeta(sessionID, ability.Meta{TeamActiveAgentMeta: name})
+}
+
+// Interact 与团队交互
+func (tm *Team) Interact(input *InteractInput) (*InteractOutput, error) {
+	return tm.InteractContext(context.Background(), input)
+}
+
+// InteractContext 由会话当前的成员处理交互，成员转交对话后由目标成员在同一轮交互中继续回复
+func (tm *Team) InteractContext(ctx context.Context, input *InteractInput) (*InteractOutput, error) {
+	if input == nil {
+		return nil, errors.New("interact input is empty")
+	}
+	if input.SessionID == "" {
+		input.SessionID = uuid.New().String()
+	}
+	unlock, err := tm.sessions.Lock(ctx, input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	meta, err := tm.memory.GetMeta(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	active := meta[TeamActiveAgentMeta] // 转交前的当前成员，回滚时恢复
+	name, err := tm.ActiveAgent(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	// 转交前的成员已经提交了消息，失败时需要回滚整轮交互
+	cp, err := tm.memory.Checkpoint(input.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	output := &InteractOutput{SessionID: input.SessionID}
+	for handoffs := 0; ; handoffs++ {
+		t := newTurn(input)
+		res, err := tm.member(name).Agent.interact(ctx, t)
+		if res != nil {
+			output.merge(res)
+		}
+		output.Agent = name
+		if err != nil {
+			return output, tm.rollback(cp, active, err)
+		}
+		if res.Status != InteractStatusHandoff {
+			return output, nil
+		}
+		if tm.maxHandoffs > 0 && handoffs >= tm.maxHandoffs {
+			return output, tm.rollback(cp, active, ErrMaxHandoffsExceeded)
+		}
+		name = res.HandoffTo
+		// 转交前的消息已存入记忆，目标成员直接根据历史继续回复
+		next := *input
+		next.Messages = nil
+		input = &next
+	}
+}
+
+// rollback 回滚整轮交互写入的消息，并恢复转交前的当前成员
+func (tm *Team) rollback(cp *memory.Checkpoint, active any, err error) error {
+	if e := tm.memory.Rollback(cp); e != nil {
+		return errors.Join(err, e)
+	}
+	// 新会话回滚时已经恢复了交互前的meta
+	if cp.Exists {
+		if e := tm.memory.UpdateMeta(cp.SessionID, ability.Meta{TeamActiveAgentMeta: active}); e != nil {
+			return errors.Join(err, e)
+		}
+	}
+	return err
+}
+
+// merge 合并一个成员的交互结果
+func (o *InteractOutput) merge(res *InteractOutput) {
+	o.Status = res.Status
+	o.Message = res.Message
+	o.Messages = append(o.Messages, res.Messages...)
+	o.Steps += res.Steps
+	o.PendingToolCalls = res.PendingToolCalls
+	o.Usage.Add(res.Usage)
+	o.HandoffTo = res.HandoffTo
+}
+
+// handoffAbility 提供转交给其他成员的工具
+type handoffAbility struct {
+	team  *Team
+	owner string
+}
+
+func (h *handoffAbility) Name() string {
+	return "handoff"
+}
+
+func (h *handoffAbility) Description() string {
+	return "transfer the conversation to another agent"
+}
+
+func (h *handoffAbility) Enable() bool {
+	return true
+}
+
+func (h *handoffAbility) Tools() (res []ability.Tool, _ error) {
+	owner := h.team.member(h.owner)
+	for _, member := range h.team.members {
+		if member.Name == h.owner || (len(owner.Handoffs) > 0 && !slices.Contains(owner.Handoffs, member.Name)) {
+			continue
+		}
+		res = append(res, ability.Tool{
+			Name:        "transfer_to_" + member.Name,
+			Enable:      true,
+			Description: fmt.Sprintf("Transfer the conversation to %s. %s", member.Name, member.Description),
+			Serial:      true,
+		})
+	}
+	return
+}
+
+func (h *handoffAbility) CallTool(opt *ability.CallToolOptions) (*message.Message, error) {
+	return h.CallToolContext(context.Background(), opt)
+}
+
+func (h *handoffAbility) CallToolContext(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+	target, ok := strings.CutPrefix(opt.Name, "transfer_to_")
+	if !ok || h.team.member(target) == nil {
+		return nil, ability.ErrAbilityToolNotFound
+	}
+	if parent := parentCallFromContext(ctx); parent != nil {
+		parent.mu.Lock()
+		parent.turn.handoff = target
+		parent.mu.Unlock()
+	}
+	// 当前成员通过工具结果写入会话meta，与其他工具更新meta的方式相同
+	return &message.Message{
+		Role:       message.RoleTool,
+		Contents:   []message.Content{message.NewMessageWithContentText("Transferred to " + target)},
+		UpdateMeta: map[string]any{TeamActiveAgentMeta: target},
+	}, nil
+}
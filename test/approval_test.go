@@ -0,0 +1,60 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestAgentApproval(t *testing.T) {
+	for _, approved := range []bool{true, false} {
+		m := &mockMind{replies: []message.Message{
+			toolCallMessage(
+				message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}},
+				message.ToolCall{ID: "2", ToolID: "0-delete", Arguments: message.ToolCallArguments{"id": 7}},
+			),
+			textMessage(message.RoleAssistant, "done"),
+		}}
+		var deleted bool
+		mock := newMockAbility()
+		mock.tools = append(mock.tools, ability.Tool{Name: "delete", Enable: true, RequireApproval: true})
+		mock.call = func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+			if opt.Name == "delete" {
+				deleted = true
+			}
+			return &message.Message{Contents: []message.Content{message.NewMessageWithContentText("ok")}}, nil
+		}
+		a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(mock)
+
+		output, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "delete record 7")}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.Status != agent.InteractStatusPendingApproval || len(output.PendingToolCalls) != 1 || output.PendingToolCalls[0].ID != "2" {
+			t.Fatalf("expected pending approval for tool call 2: %+v", output)
+		}
+		if deleted {
+			t.Fatal("tool executed before approval")
+		}
+
+		output, err = a.Resume(output.SessionID, []agent.Approval{{ToolCallID: "2", Approved: approved, Reason: "not allowed"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.Status != agent.InteractStatusCompleted || deleted != approved {
+			t.Fatalf("unexpected resume result, approved=%v deleted=%v: %+v", approved, deleted, output)
+		}
+		result := output.Messages[0]
+		if result.ToolCallID != "2" || result.IsError == approved {
+			t.Fatalf("unexpected tool result %+v", result)
+		}
+		if !approved && !strings.Contains(result.Contents[0].Text.Text, "not allowed") {
+			t.Fatalf("rejection reason not reported: %+v", result)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/budget"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestAgentBudgetTokens(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "budget.db"), 0666, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	newAgent := func() (*agent.Agent, *mockMind) {
+		m := &mockMind{
+			replies: []message.Message{
+				toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}}),
+				textMessage(message.RoleAssistant, "done"),
+			},
+			usage: usage.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+		}
+		a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(newMockAbility())
+		a.SetBudget(&budget.Budget{Session: budget.Limit{Tokens: 1000}, Store: adapters.NewBudgetBoltDBAdapter(db)})
+		return a, m
+	}
+
+	a, _ := newAgent()
+	output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	var exceeded *budget.ExceededError
+	if !errors.Is(err, agent.ErrBudgetExceeded) || !errors.As(err, &exceeded) || exceeded.Scope != budget.ScopeSession {
+		t.Fatalf("expected session budget exceeded, got %v", err)
+	}
+	if output == nil || output.Steps != 1 || len(output.Messages) != 2 {
+		t.Fatalf("unexpected partial output %+v", output)
+	}
+
+	// 使用持久化的存储，重新创建的agent仍然受限额约束
+	a, m := newAgent()
+	if _, err = a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}}); !errors.Is(err, agent.ErrBudgetExceeded) {
+		t.Fatalf("expected budget exceeded after restart, got %v", err)
+	}
+	if m.callCount() != 0 {
+		t.Fatal("mind should not be called after the budget is exceeded")
+	}
+}
+
+func TestAgentBudgetToolCalls(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(
+			message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}},
+			message.ToolCall{ID: "2", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 2}},
+		),
+		textMessage(message.RoleAssistant, "done"),
+	}}
+	memory := adapters.NewMemorySimpleAdapter(0)
+	a := agent.New().GrantMind(m).GrantMemory(memory).GrantAbility(newMockAbility())
+	a.SetBudget(&budget.Budget{Daily: budget.Limit{ToolCalls: 1}})
+
+	output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if !errors.Is(err, agent.ErrBudgetExceeded) {
+		t.Fatalf("expected budget exceeded, got %v", err)
+	}
+	// 两个工具调用中只有一个被执行，另一个返回错误结果
+	if len(output.Messages) != 3 || output.Messages[1].IsError == output.Messages[2].IsError {
+		t.Fatalf("one of the tool calls should be refused: %+v", output.Messages)
+	}
+}
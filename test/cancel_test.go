@@ -0,0 +1,127 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+// blockingMind 调用后一直等待，直到ctx取消
+type blockingMind struct {
+	started chan struct{}
+}
+
+func (m *blockingMind) Call(opt *mind.CallOptions) (*mind.CallResponse, error) {
+	return m.CallContext(context.Background(), opt)
+}
+
+func (m *blockingMind) CallContext(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+	close(m.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// interactAndCancel 在started之后取消交互，返回交互的错误
+func interactAndCancel(t *testing.T, a *agent.Agent, started chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.InteractContext(ctx, &agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+		done <- err
+	}()
+	<-started
+	cancel()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal("interaction was not interrupted by ctx")
+		return nil
+	}
+}
+
+func TestCancelMindCall(t *testing.T) {
+	m := &blockingMind{started: make(chan struct{})}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	if err := interactAndCancel(t, a, m.started); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestCancelToolCall(t *testing.T) {
+	started := make(chan struct{})
+	mock := newMockAbility()
+	mock.call = func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	a := agent.New().
+		GrantMind(&mockMind{replies: []message.Message{toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo"})}}).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(mock)
+	if err := interactAndCancel(t, a, started); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+type ctxKey struct{}
+
+// contextMemory 记录读写消息时收到的ctx
+type contextMemory struct {
+	*adapters.MemorySimpleAdapter
+	values []any
+}
+
+func (m *contextMemory) AddMessageContext(ctx context.Context, sessionID string, msg *message.Message) error {
+	m.values = append(m.values, ctx.Value(ctxKey{}))
+	return m.AddMessage(sessionID, msg)
+}
+
+func (m *contextMemory) ListMessagesContext(ctx context.Context, sessionID string, limit int) ([]message.Message, error) {
+	m.values = append(m.values, ctx.Value(ctxKey{}))
+	return m.ListMessages(sessionID, limit)
+}
+
+func TestMemoryContextHandler(t *testing.T) {
+	handler := &contextMemory{MemorySimpleAdapter: adapters.NewMemorySimpleAdapter(0)}
+	a := agent.New().
+		GrantMind(&mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "hello")}}).
+		GrantMemory(handler)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	if _, err := a.InteractContext(ctx, &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}); err != nil {
+		t.Fatal(err)
+	}
+	// 存入用户消息、读取上下文、存入回复
+	if len(handler.values) != 3 {
+		t.Fatalf("expected 3 context calls, got %d", len(handler.values))
+	}
+	for i, v := range handler.values {
+		if v != "v" {
+			t.Errorf("call %d: expected the interaction ctx, got %v", i, v)
+		}
+	}
+
+	// 不支持context的handler在ctx取消后不再读写
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	m := new(memory.Memory)
+	if err := m.SetHandler(adapters.NewMemorySimpleAdapter(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddMessageContext(cancelled, "s2", &message.Message{Role: message.RoleUser}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if exists, _ := m.HasMessageSession("s2"); exists {
+		t.Error("expected nothing to be written after ctx was cancelled")
+	}
+}
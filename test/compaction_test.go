@@ -0,0 +1,53 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestAgentCompaction(t *testing.T) {
+	memory := adapters.NewMemorySimpleAdapter(0)
+	m := &mockMind{}
+	a := agent.New().GrantMind(m).GrantMemory(memory)
+	a.SetCompaction(&agent.CompactionOptions{Threshold: 4, Keep: 2})
+
+	for i := 1; i <= 3; i++ {
+		// 压缩时思维先返回摘要，再返回正常的回复
+		m.replies = []message.Message{
+			textMessage(message.RoleAssistant, fmt.Sprintf("summary %d", i)),
+			textMessage(message.RoleAssistant, fmt.Sprintf("reply %d", i)),
+		}
+		if i < 3 {
+			m.replies = m.replies[1:]
+		}
+		if _, err := a.Interact(&agent.InteractInput{
+			SessionID: "s1",
+			Messages:  []message.Message{textMessage(message.RoleUser, fmt.Sprintf("question %d", i))},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第三轮开始时有5条消息，超过阈值，概括前3条，保留最近2条
+	summarize := m.calls[len(m.calls)-2].Messages
+	if !strings.Contains(summarize[1].Contents[0].Text.Text, "question 1") || strings.Contains(summarize[1].Contents[0].Text.Text, "question 3") {
+		t.Fatalf("unexpected summarized span: %s", summarize[1].Contents[0].Text.Text)
+	}
+	var sent []string
+	for _, msg := range m.calls[len(m.calls)-1].Messages {
+		sent = append(sent, messageText(msg))
+	}
+	want := "Summary of the earlier conversation:\nsummary 3|reply 2|question 3"
+	if got := strings.Join(sent, "|"); got != want {
+		t.Fatalf("unexpected context after compaction:\n%s", got)
+	}
+	stored, _ := memory.ListMessages("s1", 0)
+	if len(stored) != 7 {
+		t.Fatalf("history should be kept in memory, got %d messages", len(stored))
+	}
+}
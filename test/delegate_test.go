@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+)
+
+func newDelegateAgents() (coordinator, research *agent.Agent) {
+	research = agent.New().
+		GrantMind(&mockMind{
+			replies: []message.Message{textMessage(message.RoleAssistant, "180154 is in stock")},
+			usage:   usage.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40},
+		}).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	coordinator = agent.New().
+		GrantMind(&mockMind{
+			replies: []message.Message{
+				toolCallMessage(message.ToolCall{ID: "call_1", ToolID: "0-ask_research", Arguments: message.ToolCallArguments{"question": "is 180154 in stock?"}}),
+				textMessage(message.RoleAssistant, "yes"),
+			},
+			usage: usage.Usage{PromptTokens: 5, CompletionTokens: 5, TotalTokens: 10},
+		}).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(research.AsAbility(&agent.AgentAbilityOptions{Name: "research", Description: "answers stock questions"}))
+	return
+}
+
+func TestAgentAsAbility(t *testing.T) {
+	coordinator, research := newDelegateAgents()
+	output, err := coordinator.Interact(&agent.InteractInput{
+		SessionID: "parent",
+		Meta:      ability.Meta{"user_id": "u1"},
+		Messages:  []message.Message{textMessage(message.RoleUser, "check 180154")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Messages) != 3 || messageText(output.Messages[1]) != "180154 is in stock" {
+		t.Fatalf("unexpected messages %+v", output.Messages)
+	}
+
+	sub := agent.SubSessionID("parent", "call_1")
+	messages, err := research.ListMessages(sub, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messageText(messages[0]) != "is 180154 in stock?" {
+		t.Fatalf("unexpected sub-session messages %+v", messages)
+	}
+
+	// 子会话继承上级会话的meta
+	if meta, _ := research.GetMeta(sub); meta["user_id"] != "u1" {
+		t.Errorf("expected sub-session to inherit parent meta, got %v", meta)
+	}
+
+	// 上级交互的用量包含子会话的用量
+	if output.Usage.TotalTokens != 60 {
+		t.Errorf("expected nested usage to be propagated, got %+v", output.Usage)
+	}
+	total, err := coordinator.GetSessionUsage("parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.TotalTokens != 60 {
+		t.Errorf("expected session usage to include nested usage, got %+v", total)
+	}
+}
+
+func TestAgentAsAbilityStream(t *testing.T) {
+	coordinator, _ := newDelegateAgents()
+	events, err := coordinator.InteractStream(context.Background(), &agent.InteractInput{SessionID: "parent", Messages: []message.Message{textMessage(message.RoleUser, "check 180154")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var nested bool
+	for event := range events {
+		if event.ParentToolCallID == "" {
+			continue
+		}
+		nested = true
+		if event.ParentToolCallID != "call_1" || event.SessionID != agent.SubSessionID("parent", "call_1") {
+			t.Errorf("unexpected nested event %+v", event)
+		}
+	}
+	if !nested {
+		t.Error("expected nested events to be forwarded")
+	}
+}
+
+func TestDeleteSessionWithSubSessions(t *testing.T) {
+	coordinator, research := newDelegateAgents()
+	if _, err := coordinator.Interact(&agent.InteractInput{SessionID: "parent", Messages: []message.Message{textMessage(message.RoleUser, "check 180154")}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 子会话保存在子agent的记忆中，导出和删除时一并处理
+	export, err := coordinator.ExportSession("parent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(export.SubSessions) != 1 || export.SubSessions[0].Session.ID != agent.SubSessionID("parent", "call_1") || len(export.SubSessions[0].Messages) != 2 {
+		t.Fatalf("unexpected sub-sessions %+v", export.SubSessions)
+	}
+	if err = coordinator.DeleteSession("parent"); err != nil {
+		t.Fatal(err)
+	}
+	if _, total, _ := research.ListSessions(0, 0); total != 0 {
+		t.Errorf("expected sub-sessions to be deleted, got %d", total)
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestSanitizeHistory(t *testing.T) {
+	toolResult := func(id, text string) message.Message {
+		msg := textMessage(message.RoleTool, text)
+		msg.ToolCallID = id
+		return msg
+	}
+	history := []message.Message{
+		toolResult("old", "cut off by the window"),
+		textMessage(message.RoleUser, "check both"),
+		toolCallMessage(message.ToolCall{ID: "a", ToolID: "0-echo"}, message.ToolCall{ID: "b", ToolID: "0-echo"}),
+		toolResult("a", "result a"),
+		toolResult("a", "duplicate"),
+		toolResult("x", "unknown call"),
+		textMessage(message.RoleAssistant, "partial answer"),
+	}
+
+	m := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "ok")}}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).SetMergeSameRoleMessages(true)
+	if err := a.AddMessages("s1", history); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.Talk("s1", "and now?"); err != nil {
+		t.Fatal(err)
+	}
+
+	got := m.calls[0].Messages
+	want := []struct {
+		role       message.Role
+		toolCallID string
+	}{
+		{message.RoleUser, ""},
+		{message.RoleAssistant, ""},
+		{message.RoleTool, "a"},
+		{message.RoleTool, "b"},
+		{message.RoleAssistant, ""},
+		{message.RoleUser, ""},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages, got %d: %+v", len(want), len(got), got)
+	}
+	for i, w := range want {
+		if got[i].Role != w.role || got[i].ToolCallID != w.toolCallID {
+			t.Errorf("message %d: expected %s %q, got %s %q", i, w.role, w.toolCallID, got[i].Role, got[i].ToolCallID)
+		}
+	}
+	if messageText(got[2]) != "result a" {
+		t.Errorf("expected the first result to be kept, got %q", messageText(got[2]))
+	}
+	if !got[3].IsError || messageText(got[3]) != `{"error":"tool result unavailable"}` {
+		t.Errorf("expected a synthesized error result, got %+v", got[3])
+	}
+
+	// 合并相邻的同角色消息，存储的历史保持不变
+	m.replies = []message.Message{textMessage(message.RoleAssistant, "merged")}
+	if err := a.AddMessages("s1", []message.Message{textMessage(message.RoleUser, "one more")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.Talk("s1", "thing"); err != nil {
+		t.Fatal(err)
+	}
+	got = m.calls[1].Messages
+	if last := got[len(got)-1]; last.Role != message.RoleUser || len(last.Contents) != 2 || got[len(got)-2].Role != message.RoleAssistant {
+		t.Errorf("expected consecutive user messages to be merged, got %+v", got)
+	}
+	if stored, _ := a.ListMessages("s1", 0); len(stored) != len(history)+5 {
+		t.Errorf("expected stored history to be untouched, got %d messages", len(stored))
+	}
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+func TestAgentHooks(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}}),
+		textMessage(message.RoleAssistant, "secret"),
+	}}
+	mock := newMockAbility()
+	mock.call = func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+		return nil, errors.New("tool should be short-circuited")
+	}
+	var order []string
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(mock)
+	a.UseMindMiddleware(func(next agent.MindCallFunc) agent.MindCallFunc {
+		return func(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+			order = append(order, "outer")
+			return next(ctx, opt)
+		}
+	})
+	a.UseHooks(&agent.Hooks{
+		BeforeMindCall: func(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+			order = append(order, "before")
+			return nil, nil
+		},
+		AfterMindCall: func(ctx context.Context, opt *mind.CallOptions, resp *mind.CallResponse) error {
+			for i, c := range resp.Message.Contents {
+				if c.Text.Text == "secret" {
+					resp.Message.Contents[i] = message.NewMessageWithContentText("[redacted]")
+				}
+			}
+			return nil
+		},
+		BeforeToolCall: func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+			if opt.ToolCallID != "1" || opt.Name != "echo" {
+				t.Errorf("unexpected tool call options %+v", opt)
+			}
+			return &message.Message{Contents: []message.Content{message.NewMessageWithContentText("cached")}}, nil
+		},
+	})
+
+	output, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := output.Message.Contents[0].Text.Text; got != "[redacted]" {
+		t.Fatalf("response not rewritten: %q", got)
+	}
+	if result := output.Messages[1]; result.IsError || result.Contents[0].Text.Text != "cached" {
+		t.Fatalf("tool call not short-circuited: %+v", result)
+	}
+	if len(order) != 4 || order[0] != "outer" || order[1] != "before" {
+		t.Fatalf("unexpected middleware order %v", order)
+	}
+}
+
+func TestAgentErrorHook(t *testing.T) {
+	wrapped := errors.New("wrapped")
+	a := agent.New().GrantMind(&mockMind{}).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	a.UseHooks(&agent.Hooks{OnError: func(ctx context.Context, err error) error {
+		return errors.Join(wrapped, err)
+	}})
+	_, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if !errors.Is(err, wrapped) {
+		t.Fatalf("error hook not applied: %v", err)
+	}
+}
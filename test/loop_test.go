@@ -0,0 +1,288 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestAgentMaxSteps(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}}),
+		toolCallMessage(message.ToolCall{ID: "2", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 2}}),
+		toolCallMessage(message.ToolCall{ID: "3", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 3}}),
+	}}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(newMockAbility())
+
+	output, err := a.Interact(&agent.InteractInput{
+		MaxSteps: 2,
+		Messages: []message.Message{textMessage(message.RoleUser, "hi")},
+	})
+	if !errors.Is(err, agent.ErrMaxStepsExceeded) {
+		t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+	}
+	if output == nil || output.Steps != 2 || len(output.Messages) != 4 {
+		t.Fatalf("unexpected partial output: %+v", output)
+	}
+}
+
+func TestAgentRepeatedToolCall(t *testing.T) {
+	call := message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}}
+	// 第二次仍然回复相同的工具调用，触发重复检测后禁用工具强制回答
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(call),
+		toolCallMessage(call),
+		textMessage(message.RoleAssistant, "done"),
+	}}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(newMockAbility())
+	a.SetMaxRepeatedToolCalls(1).SetStepLimitPolicy(agent.StepLimitFinalAnswer)
+
+	output, err := a.Interact(&agent.InteractInput{
+		Messages: []message.Message{textMessage(message.RoleUser, "hi")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := output.Message.Contents[0].Text.Text; got != "done" {
+		t.Fatalf("unexpected final answer %q", got)
+	}
+	if last := m.calls[len(m.calls)-1]; len(last.Tools) != 0 {
+		t.Fatalf("final answer should be requested without tools")
+	}
+}
+
+func TestAgentConcurrentToolCalls(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(
+			message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}},
+			message.ToolCall{ID: "2", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 2}},
+			message.ToolCall{ID: "3", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 3}},
+		),
+		textMessage(message.RoleAssistant, "done"),
+	}}
+	// 所有工具调用都开始执行后才返回，如果依次执行则会超时
+	var started sync.WaitGroup
+	started.Add(3)
+	mock := newMockAbility()
+	mock.call = func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+		started.Done()
+		done := make(chan struct{})
+		go func() { started.Wait(); close(done) }()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			return nil, errors.New("tool calls are not executed concurrently")
+		}
+		return &message.Message{Contents: []message.Content{message.NewMessageWithContentText(opt.Args.String())}}, nil
+	}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(mock)
+
+	output, err := a.Interact(&agent.InteractInput{
+		Messages: []message.Message{textMessage(message.RoleUser, "hi")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, msg := range output.Messages {
+		if msg.Role == message.RoleTool {
+			ids = append(ids, msg.ToolCallID)
+		}
+	}
+	if strings.Join(ids, ",") != "1,2,3" {
+		t.Fatalf("tool results are not in call order: %v", ids)
+	}
+}
+
+// concurrencyTracker 记录同时执行的工具调用数量，以及Serial工具执行时是否有其他工具在执行
+type concurrencyTracker struct {
+	mu            sync.Mutex
+	running       int
+	maxRunning    int
+	serialRunning bool
+	overlapped    bool
+}
+
+func (c *concurrencyTracker) call(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+	serial := opt.Name == "write"
+	c.mu.Lock()
+	c.running++
+	c.maxRunning = max(c.maxRunning, c.running)
+	if c.serialRunning || (serial && c.running > 1) {
+		c.overlapped = true
+	}
+	c.serialRunning = c.serialRunning || serial
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	if serial {
+		c.serialRunning = false
+	}
+	c.mu.Unlock()
+	return &message.Message{Contents: []message.Content{message.NewMessageWithContentText(opt.Args.String())}}, nil
+}
+
+func TestAgentSerialToolCalls(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(
+			message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}},
+			message.ToolCall{ID: "2", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 2}},
+			message.ToolCall{ID: "3", ToolID: "0-write", Arguments: message.ToolCallArguments{"n": 3}},
+			message.ToolCall{ID: "4", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 4}},
+		),
+		textMessage(message.RoleAssistant, "done"),
+	}}
+	tracker := new(concurrencyTracker)
+	mock := newMockAbility()
+	mock.tools = append(mock.tools, ability.Tool{Name: "write", Enable: true, Serial: true})
+	mock.call = tracker.call
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(mock)
+
+	output, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 1和2同时执行，3单独执行，4在3之后执行
+	if tracker.overlapped || tracker.maxRunning != 2 {
+		t.Errorf("expected the serial tool to run alone, overlapped %v, max running %d", tracker.overlapped, tracker.maxRunning)
+	}
+	var ids []string
+	for _, msg := range output.Messages {
+		if msg.Role == message.RoleTool {
+			ids = append(ids, msg.ToolCallID)
+		}
+	}
+	if strings.Join(ids, ",") != "1,2,3,4" {
+		t.Fatalf("tool results are not in call order: %v", ids)
+	}
+}
+
+func TestAgentToolConcurrencyLimit(t *testing.T) {
+	var calls []message.ToolCall
+	for i := range 5 {
+		calls = append(calls, message.ToolCall{ID: fmt.Sprint(i), ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": i}})
+	}
+	m := &mockMind{replies: []message.Message{toolCallMessage(calls...), textMessage(message.RoleAssistant, "done")}}
+	tracker := new(concurrencyTracker)
+	mock := newMockAbility()
+	mock.call = tracker.call
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(mock).SetToolConcurrency(2)
+
+	if _, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}); err != nil {
+		t.Fatal(err)
+	}
+	if tracker.maxRunning != 2 {
+		t.Errorf("expected at most 2 tool calls at the same time, got %d", tracker.maxRunning)
+	}
+}
+
+func TestAgentToolErrorFeedback(t *testing.T) {
+	newMind := func() *mockMind {
+		return &mockMind{replies: []message.Message{
+			toolCallMessage(
+				message.ToolCall{ID: "1", ToolID: "0-missing"},
+				message.ToolCall{ID: "2", ToolID: "bad"},
+			),
+			textMessage(message.RoleAssistant, "sorry"),
+		}}
+	}
+	input := func() *agent.InteractInput {
+		return &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}
+	}
+
+	m := newMind()
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(newMockAbility())
+	output, err := a.Interact(input())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第二次调用思维时，每个工具调用都应该有对应的错误结果
+	history := m.calls[1].Messages
+	for i, id := range []string{"1", "2"} {
+		msg := history[len(history)-2+i]
+		if msg.Role != message.RoleTool || msg.ToolCallID != id || !msg.IsError {
+			t.Fatalf("missing error result for tool call %s: %+v", id, msg)
+		}
+	}
+	if output.Steps != 2 {
+		t.Fatalf("unexpected steps %d", output.Steps)
+	}
+
+	a = agent.New().GrantMind(newMind()).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(newMockAbility())
+	a.SetToolErrorPolicy(agent.ToolErrorAbort)
+	_, err = a.Interact(input())
+	var toolErr *agent.ToolCallError
+	if !errors.As(err, &toolErr) || !errors.Is(err, ability.ErrAbilityToolNotFound) {
+		t.Fatalf("expected ToolCallError, got %v", err)
+	}
+}
+
+func TestAgentInstructions(t *testing.T) {
+	m := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "hello")}}
+	memory := adapters.NewMemorySimpleAdapter(0)
+	a := agent.New().GrantMind(m).GrantMemory(memory).GrantInstructions("You are a helpful assistant, session {{.SessionID}}.")
+
+	output, err := a.Interact(&agent.InteractInput{
+		SessionID:     "s1",
+		MessagesLimit: 1,
+		Messages:      []message.Message{textMessage(message.RoleUser, "hi")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := m.calls[0].Messages
+	if len(sent) != 2 || sent[0].Role != message.RoleSystem || sent[0].Contents[0].Text.Text != "You are a helpful assistant, session s1." {
+		t.Fatalf("instructions not prepended: %+v", sent)
+	}
+	stored, _ := memory.ListMessages(output.SessionID, 0)
+	for _, msg := range stored {
+		if msg.Role == message.RoleSystem {
+			t.Fatalf("instructions should not be stored")
+		}
+	}
+}
+
+func TestAgentContextTokenBudget(t *testing.T) {
+	memory := adapters.NewMemorySimpleAdapter(0)
+	history := []message.Message{
+		textMessage(message.RoleSystem, "be brief"),
+		textMessage(message.RoleUser, "look it up"),
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo"}),
+		{Role: message.RoleTool, ToolCallID: "1", Contents: []message.Content{message.NewMessageWithContentText(strings.Repeat("data ", 2000))}},
+		textMessage(message.RoleAssistant, "found it"),
+	}
+	for _, msg := range history {
+		memory.AddMessage("s1", &msg)
+	}
+	m := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "hello")}}
+	a := agent.New().GrantMind(m).GrantMemory(memory)
+
+	_, err := a.Interact(&agent.InteractInput{
+		SessionID:        "s1",
+		MaxContextTokens: 200,
+		Messages:         []message.Message{textMessage(message.RoleUser, "thanks")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var roles []string
+	for _, msg := range m.calls[0].Messages {
+		roles = append(roles, string(msg.Role))
+	}
+	// 超出预算的工具调用与结果一起被删除，之前的消息也不再保留，system消息始终保留
+	if got := strings.Join(roles, ","); got != "system,assistant,user" {
+		t.Fatalf("unexpected trimmed history %s", got)
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestMessageIdentity(t *testing.T) {
+	for name, handler := range newMemoryAdapters(t) {
+		t.Run(name, func(t *testing.T) {
+			m := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "hello alice")}, model: "gpt-test"}
+			a := agent.New().GrantMind(m).GrantMemory(handler)
+			input := textMessage(message.RoleUser, "hi")
+			input.Name = "alice"
+			input.Metadata = message.Metadata{"channel": "web"}
+			output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{input}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if output.Message.ID == "" || output.Message.CreatedAt.IsZero() {
+				t.Fatalf("expected reply to have an id and creation time, got %+v", output.Message)
+			}
+
+			messages, err := a.ListMessages("s1", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 2 {
+				t.Fatalf("expected 2 messages, got %d", len(messages))
+			}
+			user, reply := messages[0], messages[1]
+			if user.ID == "" || user.ID == reply.ID || user.Name != "alice" || user.Metadata["channel"] != "web" {
+				t.Errorf("unexpected stored user message %+v", user)
+			}
+			if reply.ID != output.Message.ID || reply.Model != "gpt-test" || !reply.CreatedAt.Equal(output.Message.CreatedAt) {
+				t.Errorf("stored reply %+v does not match output %+v", reply, output.Message)
+			}
+			if time.Since(user.CreatedAt) > time.Minute {
+				t.Errorf("unexpected creation time %s", user.CreatedAt)
+			}
+
+			// 同一组输入消息可以重复使用，每次存入都生成新的ID
+			inputs := []message.Message{input}
+			for range 2 {
+				if _, err = a.Interact(&agent.InteractInput{SessionID: "s2", Messages: inputs}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if inputs[0].ID != "" {
+				t.Errorf("expected the caller's message to be unchanged, got id %q", inputs[0].ID)
+			}
+			if messages, _ = a.ListMessages("s2", 0); len(messages) != 4 || messages[0].ID == messages[2].ID {
+				t.Errorf("expected reused input messages to get distinct ids, got %v", messages)
+			}
+		})
+	}
+}
+
+func TestOpenAIMessageName(t *testing.T) {
+	var req openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"gpt-test","choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	a := agent.New().GrantMind(adapters.NewOpenAI(config, "gpt-test")).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	input := textMessage(message.RoleUser, "hi")
+	input.Name = "alice smith"
+	if _, err := a.Interact(&agent.InteractInput{Messages: []message.Message{input}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Name != "alice_smith" {
+		t.Errorf("unexpected request messages %+v", req.Messages)
+	}
+}
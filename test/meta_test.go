@@ -0,0 +1,87 @@
+package test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+
+	"go.etcd.io/bbolt"
+)
+
+func TestInteractMeta(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo"}),
+		textMessage(message.RoleAssistant, "done"),
+	}}
+	var seen ability.Meta
+	tool := newMockAbility()
+	tool.call = func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+		seen = opt.Meta
+		return &message.Message{
+			Contents:   []message.Content{message.NewMessageWithContentText("ordered")},
+			UpdateMeta: map[string]any{"last_order": "42", "cart": nil},
+		}, nil
+	}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0)).GrantAbility(tool)
+	if err := a.SetMeta("s1", ability.Meta{"cart": "3 items"}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := a.Interact(&agent.InteractInput{
+		SessionID: "s1",
+		Meta:      ability.Meta{"user_id": "u1", "tenant": "acme"},
+		Messages:  []message.Message{textMessage(message.RoleUser, "order it")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seen["user_id"] != "u1" || seen["tenant"] != "acme" || seen["cart"] != "3 items" {
+		t.Errorf("tool did not receive the session meta: %v", seen)
+	}
+	meta, err := a.GetMeta("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta["user_id"] != "u1" || meta["last_order"] != "42" {
+		t.Errorf("unexpected session meta %v", meta)
+	}
+	if _, ok := meta["cart"]; ok {
+		t.Errorf("expected cart to be removed, got %v", meta)
+	}
+	messages, _ := a.ListMessages("s1", 0)
+	for _, msg := range messages {
+		if msg.UpdateMeta != nil {
+			t.Errorf("meta updates should not be stored: %+v", msg)
+		}
+	}
+}
+
+func TestMemoryBoltDBMeta(t *testing.T) {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "meta.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	memory := adapters.NewMemoryBoltDBAdapter(db)
+	if err = memory.SetMeta("s1", ability.Meta{"tenant": "acme", "cart": "3 items"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = memory.UpdateMeta("s1", ability.Meta{"user_id": "u1", "cart": nil}); err != nil {
+		t.Fatal(err)
+	}
+	meta, err := memory.GetMeta("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(meta) != 2 || meta["tenant"] != "acme" || meta["user_id"] != "u1" {
+		t.Fatalf("unexpected meta %v", meta)
+	}
+	if meta, _ = memory.GetMeta("s2"); meta == nil || len(meta) != 0 {
+		t.Errorf("expected empty meta for unknown session, got %v", meta)
+	}
+}
@@ -0,0 +1,95 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/usage"
+)
+
+// mockMind 按顺序返回预设的回复，用于离线测试
+type mockMind struct {
+	replies []message.Message
+	usage   usage.Usage // 每次调用返回的用量
+	model   string
+	calls   []*mind.CallOptions
+	mu      sync.Mutex
+}
+
+func (m *mockMind) Call(opt *mind.CallOptions) (*mind.CallResponse, error) {
+	return m.CallContext(context.Background(), opt)
+}
+
+func (m *mockMind) CallContext(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, opt)
+	if len(m.replies) == 0 {
+		return nil, errors.New("no more replies")
+	}
+	reply := m.replies[0]
+	if len(m.replies) > 1 {
+		m.replies = m.replies[1:]
+	}
+	return &mind.CallResponse{Message: reply, Usage: m.usage, Model: m.model}, nil
+}
+
+func (m *mockMind) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.calls)
+}
+
+// mockAbility 提供一个echo工具，返回调用参数
+type mockAbility struct {
+	tools []ability.Tool
+	call  func(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error)
+}
+
+func newMockAbility() *mockAbility {
+	return &mockAbility{
+		tools: []ability.Tool{{Name: "echo", Enable: true, Description: "echo the arguments"}},
+	}
+}
+
+func (m *mockAbility) Name() string        { return "mock" }
+func (m *mockAbility) Description() string { return "mock ability" }
+func (m *mockAbility) Enable() bool        { return true }
+
+func (m *mockAbility) Tools() ([]ability.Tool, error) {
+	return m.tools, nil
+}
+
+func (m *mockAbility) CallTool(opt *ability.CallToolOptions) (*message.Message, error) {
+	return m.CallToolContext(context.Background(), opt)
+}
+
+func (m *mockAbility) CallToolContext(ctx context.Context, opt *ability.CallToolOptions) (*message.Message, error) {
+	if m.call != nil {
+		return m.call(ctx, opt)
+	}
+	return &message.Message{
+		Role:     message.RoleTool,
+		Contents: []message.Content{message.NewMessageWithContentText(opt.Args.String())},
+	}, nil
+}
+
+func textMessage(role message.Role, text string) message.Message {
+	return message.Message{Role: role, Contents: []message.Content{message.NewMessageWithContentText(text)}}
+}
+
+func toolCallMessage(calls ...message.ToolCall) message.Message {
+	return message.Message{Role: message.RoleAssistant, ToolCalls: calls}
+}
+
+func messageText(msg message.Message) string {
+	var res string
+	for _, c := range msg.Contents {
+		res += c.Text.Text
+	}
+	return res
+}
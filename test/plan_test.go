@@ -0,0 +1,183 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestPlanAndExecute(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		textMessage(message.RoleAssistant, `{"steps":[{"description":"look up stock"},{"description":"check price"}]}`),
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"q": "180154"}}),
+		textMessage(message.RoleAssistant, `{"status":"completed","result":"in stock"}`),
+		textMessage(message.RoleAssistant, `{"status":"failed","result":"price service is down"}`),
+		textMessage(message.RoleAssistant, `{"steps":[{"description":"estimate price from history"}]}`),
+		textMessage(message.RoleAssistant, `{"status":"completed","result":"about 10"}`),
+		textMessage(message.RoleAssistant, "180154 is in stock and costs about 10"),
+	}}
+	var plans []*agent.Plan
+	a := agent.New().
+		GrantMind(m).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(newMockAbility()).
+		SetPlanning(&agent.PlanOptions{OnPlan: func(ctx context.Context, plan *agent.Plan) error {
+			plans = append(plans, plan)
+			return nil
+		}})
+
+	output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "is 180154 available and how much?")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Status != agent.InteractStatusCompleted || messageText(output.Message) != "180154 is in stock and costs about 10" {
+		t.Fatalf("unexpected output %+v", output)
+	}
+
+	want := []agent.PlanStepStatus{agent.PlanStepCompleted, agent.PlanStepFailed, agent.PlanStepCompleted}
+	plan := output.Plan
+	if plan == nil || len(plan.Steps) != len(want) || plan.Revisions != 1 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	for i, status := range want {
+		if plan.Steps[i].Status != status {
+			t.Errorf("step %d: expected %s, got %s", i+1, status, plan.Steps[i].Status)
+		}
+	}
+	if len(plans) != 2 {
+		t.Errorf("expected OnPlan to be called for the plan and its revision, got %d", len(plans))
+	}
+
+	// 制定计划时不提供工具，执行步骤时提供工具
+	if m.calls[0].Tools != nil || m.calls[0].ResponseFormat == nil || m.calls[0].ResponseFormat.Name != "plan" {
+		t.Errorf("unexpected planning call %+v", m.calls[0])
+	}
+	if len(m.calls[1].Tools) == 0 || m.calls[1].ResponseFormat.Name != "plan_step" {
+		t.Errorf("unexpected step call %+v", m.calls[1])
+	}
+	if last := m.calls[6]; last.Tools != nil || last.ResponseFormat != nil {
+		t.Errorf("unexpected synthesis call %+v", last)
+	}
+
+	stored, err := a.GetPlan("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil || len(stored.Steps) != 3 || stored.Steps[2].Result != "about 10" {
+		t.Errorf("unexpected stored plan %+v", stored)
+	}
+}
+
+func TestPlanRejected(t *testing.T) {
+	m := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, `{"steps":[{"description":"delete everything"}]}`)}}
+	rejected := context.Canceled
+	a := agent.New().
+		GrantMind(m).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		SetPlanning(&agent.PlanOptions{OnPlan: func(ctx context.Context, plan *agent.Plan) error { return rejected }})
+	output, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "clean up")}})
+	if err != rejected {
+		t.Fatalf("expected plan to be rejected, got %v", err)
+	}
+	if m.callCount() != 1 || output == nil || output.Plan != nil {
+		t.Errorf("expected nothing to run after the plan was rejected")
+	}
+}
+
+func TestPlanFullSize(t *testing.T) {
+	// 每个步骤单独计算步数，10个步骤各调用一次工具不会超过默认的步数限制
+	steps := make([]string, agent.DefaultPlanMaxSteps)
+	for i := range steps {
+		steps[i] = fmt.Sprintf(`{"description":"step %d"}`, i+1)
+	}
+	replies := []message.Message{textMessage(message.RoleAssistant, `{"steps":[`+strings.Join(steps, ",")+`]}`)}
+	for i := range steps {
+		replies = append(replies,
+			toolCallMessage(message.ToolCall{ID: fmt.Sprint(i), ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": i}}),
+			textMessage(message.RoleAssistant, `{"status":"completed","result":"done"}`),
+		)
+	}
+	replies = append(replies, textMessage(message.RoleAssistant, "all done"))
+	a := agent.New().
+		GrantMind(&mockMind{replies: replies}).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(newMockAbility()).
+		SetPlanning(&agent.PlanOptions{})
+
+	output, err := a.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "do everything")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Status != agent.InteractStatusCompleted || messageText(output.Message) != "all done" || len(output.Plan.Steps) != len(steps) {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if output.Steps <= agent.DefaultMaxSteps {
+		t.Errorf("expected the plan to take more than %d steps in total, got %d", agent.DefaultMaxSteps, output.Steps)
+	}
+}
+
+func TestPlanResumeAfterApproval(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		textMessage(message.RoleAssistant, `{"steps":[{"description":"delete record"},{"description":"confirm"}]}`),
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-delete"}),
+		textMessage(message.RoleAssistant, `{"status":"completed","result":"deleted"}`),
+		textMessage(message.RoleAssistant, `{"status":"completed","result":"confirmed"}`),
+		textMessage(message.RoleAssistant, "record deleted"),
+	}}
+	mock := newMockAbility()
+	mock.tools = append(mock.tools, ability.Tool{Name: "delete", Enable: true, RequireApproval: true})
+	a := agent.New().
+		GrantMind(m).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(mock).
+		SetPlanning(&agent.PlanOptions{})
+
+	output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "delete record 7")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Status != agent.InteractStatusPendingApproval || output.Plan.Steps[0].Status != agent.PlanStepRunning {
+		t.Fatalf("expected the first step to pause for approval, got %+v", output)
+	}
+	pending := output.Plan
+
+	// 修改获取到的计划不影响保存的计划
+	plan, err := a.GetPlan("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan.Steps[0].Status = agent.PlanStepFailed
+	if plan, _ = a.GetPlan("s1"); plan.Steps[0].Status != agent.PlanStepRunning {
+		t.Errorf("expected the stored plan to be unchanged, got %s", plan.Steps[0].Status)
+	}
+
+	// 确认后继续执行暂停的步骤和之后的步骤
+	output, err = a.Resume("s1", []agent.Approval{{ToolCallID: "1", Approved: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Steps[0].Status != agent.PlanStepRunning || pending.Steps[1].Status != agent.PlanStepPending {
+		t.Errorf("expected the plan returned with the pending result to be unchanged, got %+v", pending)
+	}
+	if output.Status != agent.InteractStatusCompleted || messageText(output.Message) != "record deleted" {
+		t.Fatalf("unexpected resume output %+v", output)
+	}
+	if m.calls[2].ResponseFormat == nil || m.calls[2].ResponseFormat.Name != "plan_step" {
+		t.Errorf("expected the paused step to continue with its format, got %+v", m.calls[2])
+	}
+	plan, err = a.GetPlan("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, step := range plan.Steps {
+		if step.Status != agent.PlanStepCompleted {
+			t.Errorf("step %d: expected completed, got %s", i+1, step.Status)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestRegenerateAndEditMessage(t *testing.T) {
+	for name, handler := range newMemoryAdapters(t) {
+		t.Run(name, func(t *testing.T) {
+			m := &mockMind{replies: []message.Message{
+				toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"q": "a"}}),
+				textMessage(message.RoleAssistant, "first"),
+				textMessage(message.RoleAssistant, "second"),
+				textMessage(message.RoleAssistant, "edited"),
+			}}
+			a := agent.New().GrantMind(m).GrantMemory(handler).GrantAbility(newMockAbility())
+			if _, _, err := a.Talk("s1", "hello"); err != nil {
+				t.Fatal(err)
+			}
+
+			// 重新生成时丢弃工具调用、工具结果和回复
+			output, err := a.Regenerate("s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if messageText(output.Message) != "second" {
+				t.Fatalf("unexpected output %+v", output)
+			}
+			messages, err := a.ListMessages("s1", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 2 || messageText(messages[0]) != "hello" || messageText(messages[1]) != "second" {
+				t.Fatalf("unexpected history after regenerate %v", messages)
+			}
+			if n := len(m.calls[2].Messages); n != 1 {
+				t.Errorf("expected the mind to only see the user message, got %d messages", n)
+			}
+
+			output, err = a.EditMessage("s1", messages[0].ID, []message.Content{message.NewMessageWithContentText("hi")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messages, _ = a.ListMessages("s1", 0)
+			if len(messages) != 2 || messageText(messages[0]) != "hi" || messageText(messages[1]) != "edited" {
+				t.Fatalf("unexpected history after edit %v", messages)
+			}
+			if s, _ := a.GetSession("s1"); s.MessageCount != 2 {
+				t.Errorf("expected message count 2, got %d", s.MessageCount)
+			}
+
+			// 重新执行失败时恢复原有的历史
+			m.mu.Lock()
+			m.replies = nil
+			m.mu.Unlock()
+			if _, err = a.Regenerate("s1"); err == nil {
+				t.Fatal("expected regenerate to fail")
+			}
+			if _, err = a.EditMessage("s1", messages[0].ID, []message.Content{message.NewMessageWithContentText("bye")}); err == nil {
+				t.Fatal("expected edit to fail")
+			}
+			restored, _ := a.ListMessages("s1", 0)
+			if len(restored) != 2 || messageText(restored[0]) != "hi" || messageText(restored[1]) != "edited" || restored[1].ID != messages[1].ID {
+				t.Fatalf("expected history to be restored after failures, got %v", restored)
+			}
+			if s, _ := a.GetSession("s1"); s.MessageCount != 2 {
+				t.Errorf("expected message count 2 after failures, got %d", s.MessageCount)
+			}
+
+			if _, err = a.EditMessage("s1", messages[1].ID, nil); !errors.Is(err, agent.ErrMessageNotEditable) {
+				t.Errorf("expected ErrMessageNotEditable, got %v", err)
+			}
+			if _, err = a.EditMessage("s1", "missing", nil); !errors.Is(err, memory.ErrMessageNotFound) {
+				t.Errorf("expected ErrMessageNotFound, got %v", err)
+			}
+			if err = a.AddMessages("s2", []message.Message{textMessage(message.RoleAssistant, "welcome")}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = a.Regenerate("s2"); !errors.Is(err, agent.ErrNoMessageToRegenerate) {
+				t.Errorf("expected ErrNoMessageToRegenerate, got %v", err)
+			}
+		})
+	}
+}
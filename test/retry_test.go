@@ -0,0 +1,134 @@
+package test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// failingServer 前failures次请求返回status，之后返回正常回复
+type failingServer struct {
+	*httptest.Server
+	requests atomic.Int32
+}
+
+func newFailingServer(failures int, status int, retryAfter, model string) *failingServer {
+	s := &failingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if int(s.requests.Add(1)) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":{"message":"failed","type":"server_error"}}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"` + model + `","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	return s
+}
+
+func newServerOpenAI(s *failingServer, model string) *adapters.OpenAI {
+	config := openai.DefaultConfig("test")
+	config.BaseURL = s.URL
+	return adapters.NewOpenAI(config, model)
+}
+
+var retryOptions = &mind.CallOptions{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}
+
+func TestOpenAIStatusError(t *testing.T) {
+	s := newFailingServer(1, http.StatusTooManyRequests, "2", "primary")
+	defer s.Close()
+
+	_, err := newServerOpenAI(s, "primary").Call(retryOptions)
+	var se *mind.StatusError
+	if !errors.As(err, &se) {
+		t.Fatalf("expected StatusError, got %v", err)
+	}
+	if se.StatusCode != http.StatusTooManyRequests || se.RetryAfter != 2*time.Second {
+		t.Errorf("unexpected status error: %d %s", se.StatusCode, se.RetryAfter)
+	}
+	if !mind.IsRetryable(err) {
+		t.Error("429 should be retryable")
+	}
+}
+
+func TestRetryRecovers(t *testing.T) {
+	s := newFailingServer(2, http.StatusServiceUnavailable, "", "primary")
+	defer s.Close()
+
+	a := agent.New().
+		GrantMind(mind.NewRetry(newServerOpenAI(s, "primary")).SetBackoff(time.Millisecond, 10*time.Millisecond)).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	_, reply, err := a.Talk("retry", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply != "ok" {
+		t.Errorf("unexpected reply %q", reply)
+	}
+	if n := s.requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	s := newFailingServer(10, http.StatusInternalServerError, "", "primary")
+	defer s.Close()
+
+	_, err := mind.NewRetry(newServerOpenAI(s, "primary")).
+		SetMaxRetries(2).
+		SetBackoff(time.Millisecond, 10*time.Millisecond).
+		Call(retryOptions)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if n := s.requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRetryFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		retryAfter string
+	}{
+		{"not retryable", http.StatusBadRequest, ""},
+		{"retry after too long", http.StatusTooManyRequests, "60"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := newFailingServer(10, tt.status, tt.retryAfter, "primary")
+			defer primary.Close()
+			fallback := newFailingServer(0, 0, "", "cheap")
+			defer fallback.Close()
+
+			resp, err := mind.NewRetry(newServerOpenAI(primary, "primary")).
+				SetBackoff(time.Millisecond, time.Second).
+				SetFallbacks(newServerOpenAI(fallback, "cheap")).
+				Call(retryOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Model != "cheap" {
+				t.Errorf("expected fallback model, got %q", resp.Model)
+			}
+			if n := primary.requests.Load(); n != 1 {
+				t.Errorf("expected primary to be called once, got %d", n)
+			}
+		})
+	}
+}
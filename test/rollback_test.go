@@ -0,0 +1,113 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestRollbackFailedTurn(t *testing.T) {
+	for name, handler := range newMemoryAdapters(t) {
+		t.Run(name, func(t *testing.T) {
+			m := &mockMind{replies: []message.Message{
+				textMessage(message.RoleAssistant, "hello"),
+				toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"q": "a"}}),
+			}}
+			a := agent.New().GrantMind(m).GrantMemory(handler).GrantAbility(newMockAbility())
+			if _, _, err := a.Talk("s1", "hi"); err != nil {
+				t.Fatal(err)
+			}
+
+			// 工具调用之后达到步数限制，本轮写入的用户消息、工具调用和工具结果全部回滚
+			output, err := a.Interact(&agent.InteractInput{
+				SessionID: "s1",
+				MaxSteps:  1,
+				Messages:  []message.Message{textMessage(message.RoleUser, "look it up")},
+			})
+			if !errors.Is(err, agent.ErrMaxStepsExceeded) {
+				t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+			}
+			if len(output.Messages) != 2 {
+				t.Errorf("expected output to keep the failed turn's messages, got %d", len(output.Messages))
+			}
+			messages, err := a.ListMessages("s1", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 2 || messageText(messages[0]) != "hi" || messageText(messages[1]) != "hello" {
+				t.Fatalf("expected only the first turn to remain, got %v", messages)
+			}
+			if s, _ := a.GetSession("s1"); s.MessageCount != 2 {
+				t.Errorf("expected message count 2, got %d", s.MessageCount)
+			}
+
+			// 新会话的第一轮交互失败时删除整个会话，之前设置的meta保留
+			if err = a.SetMeta("s2", ability.Meta{"user_id": "u1"}); err != nil {
+				t.Fatal(err)
+			}
+			m.replies = []message.Message{toolCallMessage(message.ToolCall{ID: "2", ToolID: "0-echo"})}
+			for _, id := range []string{"s2", "s3"} {
+				_, err = a.Interact(&agent.InteractInput{
+					SessionID: id,
+					MaxSteps:  1,
+					Meta:      ability.Meta{"tenant": "t1"},
+					Messages:  []message.Message{textMessage(message.RoleUser, "look it up")},
+				})
+				if !errors.Is(err, agent.ErrMaxStepsExceeded) {
+					t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+				}
+			}
+			if _, total, _ := a.ListSessions(0, 0); total != 1 {
+				t.Errorf("expected failed new sessions to be deleted, got %d sessions", total)
+			}
+			if meta, _ := a.GetMeta("s2"); len(meta) != 1 || meta["user_id"] != "u1" {
+				t.Errorf("expected meta set before the turn to be kept, got %v", meta)
+			}
+			if meta, _ := a.GetMeta("s3"); len(meta) != 0 {
+				t.Errorf("expected input meta to be removed, got %v", meta)
+			}
+		})
+	}
+}
+
+func TestRollbackCappedMemory(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		textMessage(message.RoleAssistant, "hello"),
+		toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo"}),
+	}}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(2)).GrantAbility(newMockAbility())
+	if _, _, err := a.Talk("s1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	messages, _ := a.ListMessages("s1", 0)
+
+	// 失败的一轮挤出了之前的全部消息，回滚后不能留下工具调用和工具结果
+	_, err := a.Interact(&agent.InteractInput{
+		SessionID: "s1",
+		MaxSteps:  1,
+		Messages:  []message.Message{textMessage(message.RoleUser, "look it up")},
+	})
+	if !errors.Is(err, agent.ErrMaxStepsExceeded) {
+		t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+	}
+	if got, _ := a.ListMessages("s1", 0); len(got) != 0 {
+		t.Fatalf("expected the failed turn to be rolled back, got %v", got)
+	}
+
+	// 重新生成失败时恢复原有的历史，不重复写入
+	if err = a.AddMessages("s1", messages); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.RegenerateContext(context.Background(), &agent.InteractInput{SessionID: "s1", MaxSteps: 1}); !errors.Is(err, agent.ErrMaxStepsExceeded) {
+		t.Fatalf("expected ErrMaxStepsExceeded, got %v", err)
+	}
+	got, _ := a.ListMessages("s1", 0)
+	if len(got) != 2 || got[0].ID != messages[0].ID || got[1].ID != messages[1].ID {
+		t.Fatalf("expected the original history to be restored, got %v", got)
+	}
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+func newRouteMind(model string) *mockMind {
+	return &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "from "+model)}, model: model}
+}
+
+func TestRouter(t *testing.T) {
+	imageMessage := message.Message{Role: message.RoleUser, Contents: []message.Content{message.NewMessageWithContentImage("https://example.com/a.png")}}
+	tests := []struct {
+		name     string
+		input    *agent.InteractInput
+		tools    bool
+		expected string
+	}{
+		{"default", &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}, false, "cheap"},
+		{"explicit", &agent.InteractInput{Model: "vision", Messages: []message.Message{textMessage(message.RoleUser, "hi")}}, true, "vision"},
+		{"tools", &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}, true, "smart"},
+		{"images", &agent.InteractInput{Messages: []message.Message{imageMessage}}, false, "vision"},
+		{"length", &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "a very long question")}}, false, "smart"},
+		{"classifier", &agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "urgent")}}, false, "vision"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := mind.NewRouter("cheap", newRouteMind("cheap")).
+				Register("smart", newRouteMind("smart")).
+				Register("vision", newRouteMind("vision")).
+				AddRule(
+					func(ctx context.Context, opt *mind.CallOptions) (string, error) {
+						if messageText(opt.Messages[len(opt.Messages)-1]) == "urgent" {
+							return "vision", nil
+						}
+						return "", nil
+					},
+					mind.RouteWithImages("vision"),
+					mind.RouteWithTools("smart"),
+					mind.RouteByLength(10, "smart"),
+				)
+			a := agent.New().GrantMind(router).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+			if tt.tools {
+				a.GrantAbility(newMockAbility())
+			}
+			out, err := a.Interact(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Message.Model != tt.expected {
+				t.Errorf("expected model %q, got %q", tt.expected, out.Message.Model)
+			}
+			messages, err := a.ListMessages(out.SessionID, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if last := messages[len(messages)-1]; last.Model != tt.expected {
+				t.Errorf("expected stored model %q, got %q", tt.expected, last.Model)
+			}
+		})
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	a := agent.New().
+		GrantMind(mind.NewRouter("cheap", newRouteMind("cheap"))).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	_, err := a.Interact(&agent.InteractInput{Model: "missing", Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if !errors.Is(err, mind.ErrMindRouteNotFound) {
+		t.Errorf("expected ErrMindRouteNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+// barrierMind 等待指定数量的调用同时到达后才返回
+type barrierMind struct {
+	wg sync.WaitGroup
+}
+
+func (m *barrierMind) Call(opt *mind.CallOptions) (*mind.CallResponse, error) {
+	return m.CallContext(context.Background(), opt)
+}
+
+func (m *barrierMind) CallContext(ctx context.Context, opt *mind.CallOptions) (*mind.CallResponse, error) {
+	m.wg.Done()
+	done := make(chan struct{})
+	go func() { m.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return &mind.CallResponse{Message: textMessage(message.RoleAssistant, "ok")}, nil
+	case <-time.After(time.Second):
+		return nil, errors.New("sessions are not served in parallel")
+	}
+}
+
+func TestAgentParallelSessions(t *testing.T) {
+	m := &barrierMind{}
+	m.wg.Add(2)
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i, sessionID := range []string{"a", "b"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = a.Interact(&agent.InteractInput{
+				SessionID: sessionID,
+				Messages:  []message.Message{textMessage(message.RoleUser, "hi")},
+			})
+		}()
+	}
+	// 交互过程中修改能力列表
+	a.GrantAbility(newMockAbility())
+	a.ClearAbilities()
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/memory"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+
+	"go.etcd.io/bbolt"
+)
+
+func newMemoryAdapters(t *testing.T) map[string]memory.Handler {
+	db, err := bbolt.Open(filepath.Join(t.TempDir(), "sessions.db"), 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return map[string]memory.Handler{
+		"simple": adapters.NewMemorySimpleAdapter(0),
+		"boltdb": adapters.NewMemoryBoltDBAdapter(db),
+	}
+}
+
+func TestSessionManagement(t *testing.T) {
+	for name, handler := range newMemoryAdapters(t) {
+		t.Run(name, func(t *testing.T) {
+			a := agent.New().GrantMemory(handler)
+			for _, id := range []string{"a", "b", "c"} {
+				for i := range 12 {
+					if err := a.AddMessages(id, []message.Message{textMessage(message.RoleUser, fmt.Sprint(i))}); err != nil {
+						t.Fatal(err)
+					}
+				}
+				time.Sleep(time.Millisecond)
+			}
+			if err := a.SetMeta("b", ability.Meta{"user_id": "u1"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := handler.AddUsage("b", usage.Usage{TotalTokens: 10}); err != nil {
+				t.Fatal(err)
+			}
+
+			// 按最后更新时间倒序分页
+			sessions, total, err := a.ListSessions(1, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if total != 3 || len(sessions) != 1 || sessions[0].ID != "b" || sessions[0].MessageCount != 12 {
+				t.Fatalf("unexpected page %+v (total %d)", sessions, total)
+			}
+			if s := sessions[0]; s.CreatedAt.IsZero() || s.UpdatedAt.Before(s.CreatedAt) {
+				t.Errorf("unexpected timestamps %+v", s)
+			}
+
+			// 消息超过9条时仍然保持顺序
+			messages, err := a.ListMessages("b", 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 3 || messageText(messages[0]) != "9" || messageText(messages[2]) != "11" {
+				t.Errorf("unexpected latest messages %v", messages)
+			}
+
+			if err = a.RenameSession("b", "refund"); err != nil {
+				t.Fatal(err)
+			}
+			forked, err := a.ForkSession("b", 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			export, err := a.ExportSession(forked)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if export.Session.Title != "refund" || len(export.Messages) != 10 || messageText(export.Messages[9]) != "9" {
+				t.Errorf("unexpected fork %+v", export.Session)
+			}
+			if export.Meta["user_id"] != "u1" || !export.Usage.IsZero() {
+				t.Errorf("expected fork to copy meta but not usage, got %v %+v", export.Meta, export.Usage)
+			}
+			if err = handler.ForkSession("b", forked, 0); !errors.Is(err, memory.ErrSessionExists) {
+				t.Errorf("expected ErrSessionExists, got %v", err)
+			}
+
+			if err = a.DeleteSession("b"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = a.GetSession("b"); !errors.Is(err, memory.ErrSessionNotFound) {
+				t.Errorf("expected ErrSessionNotFound, got %v", err)
+			}
+			if exists, _ := a.HasMessageSession("b", nil); exists {
+				t.Error("expected messages to be deleted")
+			}
+			if meta, _ := a.GetMeta("b"); len(meta) != 0 {
+				t.Errorf("expected meta to be deleted, got %v", meta)
+			}
+			if u, _ := a.GetSessionUsage("b"); !u.IsZero() {
+				t.Errorf("expected usage to be deleted, got %+v", u)
+			}
+			if _, total, _ = a.ListSessions(0, 0); total != 3 {
+				t.Errorf("expected 3 sessions after fork and delete, got %d", total)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// 第一次请求返回被切割的工具调用参数，第二次请求返回文本
+var streamChunks = [][]string{
+	{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"0-echo","arguments":""}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"q\":"}}]}}]}`,
+		`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"180154\"}"}}]}}]}`,
+	},
+	{
+		`{"choices":[{"index":0,"delta":{"role":"assistant","content":"in "}}]}`,
+		`{"choices":[{"index":0,"delta":{"content":"stock"}}]}`,
+		`{"choices":[],"usage":{"prompt_tokens":20,"completion_tokens":2,"total_tokens":22,"prompt_tokens_details":{"cached_tokens":8}}}`,
+	},
+}
+
+func newStreamServer(t *testing.T) *httptest.Server {
+	var n atomic.Int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(n.Add(1)) - 1
+		if i >= len(streamChunks) {
+			t.Errorf("unexpected request %d", i)
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range streamChunks[i] {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+}
+
+func TestAgentInteractStream(t *testing.T) {
+	server := newStreamServer(t)
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	a := agent.New().
+		GrantMind(adapters.NewOpenAI(config, "gpt-test")).
+		GrantMemory(adapters.NewMemorySimpleAdapter(0)).
+		GrantAbility(newMockAbility())
+
+	events, err := a.InteractStream(context.Background(), &agent.InteractInput{
+		Messages: []message.Message{textMessage(message.RoleUser, "180154有货吗？")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text, args strings.Builder
+	var finished *message.ToolCall
+	var result, last agent.StreamEvent
+	for event := range events {
+		switch event.Type {
+		case agent.StreamEventTextDelta:
+			text.WriteString(event.Text)
+		case agent.StreamEventToolCallArguments:
+			args.WriteString(event.Arguments)
+		case agent.StreamEventToolCallFinish:
+			finished = event.ToolCall
+		case agent.StreamEventToolResult:
+			result = event
+		}
+		last = event
+	}
+	if last.Type != agent.StreamEventDone {
+		t.Fatalf("expected done event, got %s %v", last.Type, last.Err)
+	}
+	if args.String() != `{"q":"180154"}` {
+		t.Fatalf("unexpected argument deltas %q", args.String())
+	}
+	if finished == nil || finished.ID != "call_1" || finished.Arguments["q"] != "180154" {
+		t.Fatalf("tool call arguments not reassembled: %+v", finished)
+	}
+	if result.Message == nil || result.Message.ToolCallID != "call_1" {
+		t.Fatalf("unexpected tool result: %+v", result.Message)
+	}
+	if u := last.Output.Usage; u.TotalTokens != 22 || u.CachedTokens != 8 {
+		t.Fatalf("usage not captured from stream: %+v", u)
+	}
+	if text.String() != "in stock" || last.Output.Steps != 2 {
+		t.Fatalf("unexpected final output %q, %+v", text.String(), last.Output)
+	}
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/schema"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type stockInfo struct {
+	SKU     string `json:"sku" description:"product code"`
+	InStock bool   `json:"in_stock"`
+	Count   int    `json:"count"`
+	Note    string `json:"note,omitempty"`
+}
+
+func TestInteractTyped(t *testing.T) {
+	m := &mockMind{replies: []message.Message{
+		textMessage(message.RoleAssistant, `{"sku":"180154","in_stock":"yes"}`),
+		textMessage(message.RoleAssistant, "```json\n{\"sku\":\"180154\",\"in_stock\":true,\"count\":3}\n```"),
+	}}
+	a := agent.New().GrantMind(m).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+
+	input := &agent.InteractInput{
+		SessionID: "s1",
+		Messages:  []message.Message{textMessage(message.RoleUser, "180154有货吗？")},
+	}
+	res, output, err := agent.InteractTyped[stockInfo](context.Background(), a, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.ResponseFormat != nil {
+		t.Error("expected the caller's input to be left unchanged")
+	}
+	if *res != (stockInfo{SKU: "180154", InStock: true, Count: 3}) || output.Steps != 2 {
+		t.Fatalf("unexpected result %+v, steps %d", res, output.Steps)
+	}
+	// 第二次调用思维时带有格式错误的反馈
+	feedback := m.calls[1].Messages[len(m.calls[1].Messages)-1]
+	if feedback.Role != message.RoleUser || !strings.Contains(messageText(feedback), `missing required property "count"`) {
+		t.Fatalf("validation error not fed back: %+v", feedback)
+	}
+	if f := m.calls[0].ResponseFormat; f == nil || f.Name != "stockInfo" || f.Strict {
+		t.Fatalf("unexpected response format %+v", f)
+	}
+	// 格式错误的回复和纠正提示不存入记忆
+	stored, err := a.ListMessages("s1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 || stored[0].Role != message.RoleUser || !strings.Contains(messageText(stored[1]), `"count":3`) {
+		t.Fatalf("expected only the question and the valid reply to be stored, got %v", stored)
+	}
+}
+
+type itemBase struct {
+	ID string `json:"id"`
+}
+
+type ItemExtra struct {
+	Tag string `json:"tag"`
+}
+
+type item struct {
+	itemBase
+	*ItemExtra
+	Name  string  `json:"name"`
+	Price *int    `json:"price"`
+	Owner *string `json:"owner,omitempty"`
+}
+
+func TestSchemaFollowsEncodingJSON(t *testing.T) {
+	s, err := schema.For[item]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 嵌入的结构体字段展开到外层，嵌入的指针字段为非必填
+	if _, ok := s.Properties["itemBase"]; ok || s.Properties["id"] == nil || s.Properties["tag"] == nil {
+		t.Fatalf("expected embedded fields to be inlined, got %+v", s.Properties)
+	}
+	if strings.Join(s.Required, ",") != "name,price,id" {
+		t.Errorf("unexpected required properties %v", s.Required)
+	}
+	for _, v := range []item{{itemBase: itemBase{ID: "1"}, Name: "pen"}, {ItemExtra: &ItemExtra{Tag: "new"}}} {
+		data, _ := json.Marshal(v)
+		if err = s.Validate(data); err != nil {
+			t.Errorf("expected %s to be valid, got %v", data, err)
+		}
+	}
+	if err = s.Validate([]byte(`{"id":"1","name":"pen","price":"10"}`)); err == nil {
+		t.Error("expected a string price to be rejected")
+	}
+	data, _ := json.Marshal(s.Properties["price"])
+	if string(data) != `{"type":["integer","null"]}` {
+		t.Errorf("unexpected pointer schema %s", data)
+	}
+}
+
+func TestOpenAIResponseFormat(t *testing.T) {
+	var format map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		format, _ = req["response_format"].(map[string]any)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"sku\":\"1\",\"in_stock\":false,\"count\":0}"}}]}`))
+	}))
+	defer server.Close()
+
+	config := openai.DefaultConfig("test")
+	config.BaseURL = server.URL
+	a := agent.New().GrantMind(adapters.NewOpenAI(config, "gpt-test")).GrantMemory(adapters.NewMemorySimpleAdapter(0))
+	if _, _, err := agent.InteractTyped[stockInfo](context.Background(), a, &agent.InteractInput{
+		Messages: []message.Message{textMessage(message.RoleUser, "hi")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	jsonSchema, _ := format["json_schema"].(map[string]any)
+	if format["type"] != "json_schema" || jsonSchema["name"] != "stockInfo" || jsonSchema["schema"] == nil {
+		t.Fatalf("unexpected response_format %v", format)
+	}
+}
@@ -0,0 +1,100 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+)
+
+func TestTeamHandoff(t *testing.T) {
+	triage := &mockMind{replies: []message.Message{
+		toolCallMessage(message.ToolCall{ID: "call_1", ToolID: "0-transfer_to_billing"}),
+	}}
+	billing := &mockMind{replies: []message.Message{textMessage(message.RoleAssistant, "refund issued")}}
+	memory := adapters.NewMemorySimpleAdapter(0)
+	team, err := agent.NewTeam(memory,
+		agent.TeamMember{Name: "triage", Description: "routes requests", Agent: agent.New().GrantMind(triage)},
+		agent.TeamMember{Name: "billing", Description: "handles refunds", Agent: agent.New().GrantMind(billing)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := team.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "refund please")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.Agent != "billing" || output.Status != agent.InteractStatusCompleted || messageText(output.Message) != "refund issued" {
+		t.Fatalf("unexpected output %+v", output)
+	}
+	if len(output.Messages) != 3 || output.Steps != 2 {
+		t.Errorf("expected transfer call, tool result and reply, got %d messages in %d steps", len(output.Messages), output.Steps)
+	}
+	if tools := triage.calls[0].Tools; len(tools) != 1 || tools[0].Name != "transfer_to_billing" {
+		t.Errorf("unexpected triage tools %+v", tools)
+	}
+	active, err := team.ActiveAgent("s1")
+	if err != nil || active != "billing" {
+		t.Fatalf("expected billing to be active, got %q %v", active, err)
+	}
+
+	// 之后的交互直接由billing处理，并且可以看到转交前的历史
+	if _, err = team.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "thanks")}}); err != nil {
+		t.Fatal(err)
+	}
+	if triage.callCount() != 1 || billing.callCount() != 2 {
+		t.Errorf("unexpected calls: triage %d, billing %d", triage.callCount(), billing.callCount())
+	}
+	if n := len(billing.calls[1].Messages); n != 5 {
+		t.Errorf("expected billing to see the shared history, got %d messages", n)
+	}
+}
+
+func TestTeamMaxHandoffs(t *testing.T) {
+	ping := &mockMind{replies: []message.Message{toolCallMessage(message.ToolCall{ID: "a", ToolID: "0-transfer_to_pong"})}}
+	pong := &mockMind{replies: []message.Message{toolCallMessage(message.ToolCall{ID: "b", ToolID: "0-transfer_to_ping"})}}
+	team, err := agent.NewTeam(adapters.NewMemorySimpleAdapter(0),
+		agent.TeamMember{Name: "ping", Agent: agent.New().GrantMind(ping)},
+		agent.TeamMember{Name: "pong", Agent: agent.New().GrantMind(pong)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	team.SetMaxHandoffs(2)
+	if _, err = team.Interact(&agent.InteractInput{Messages: []message.Message{textMessage(message.RoleUser, "hi")}}); err != agent.ErrMaxHandoffsExceeded {
+		t.Fatalf("expected ErrMaxHandoffsExceeded, got %v", err)
+	}
+}
+
+func TestTeamRollbackHandoff(t *testing.T) {
+	triage := &mockMind{replies: []message.Message{
+		textMessage(message.RoleAssistant, "how can I help?"),
+		toolCallMessage(message.ToolCall{ID: "call_1", ToolID: "0-transfer_to_billing"}),
+	}}
+	billing := &mockMind{} // 没有回复，调用失败
+	memory := adapters.NewMemorySimpleAdapter(0)
+	team, err := agent.NewTeam(memory,
+		agent.TeamMember{Name: "triage", Agent: agent.New().GrantMind(triage)},
+		agent.TeamMember{Name: "billing", Agent: agent.New().GrantMind(billing)},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = team.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = team.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "refund please")}}); err == nil {
+		t.Fatal("expected billing to fail")
+	}
+
+	// 转交后失败时，消息和当前成员都恢复到本轮交互之前
+	active, err := team.ActiveAgent("s1")
+	if err != nil || active != "triage" {
+		t.Errorf("expected triage to be active after rollback, got %q %v", active, err)
+	}
+	if messages, _ := memory.ListMessages("s1", 0); len(messages) != 2 {
+		t.Errorf("expected only the first turn to remain, got %d messages", len(messages))
+	}
+}
@@ -0,0 +1,45 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/deep-project/agent"
+	"github.com/deep-project/agent/adapters"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/usage"
+)
+
+func TestAgentUsage(t *testing.T) {
+	m := &mockMind{
+		replies: []message.Message{
+			toolCallMessage(message.ToolCall{ID: "1", ToolID: "0-echo", Arguments: message.ToolCallArguments{"n": 1}}),
+			textMessage(message.RoleAssistant, "done"),
+		},
+		usage: usage.Usage{PromptTokens: 1000, CachedTokens: 400, CompletionTokens: 100, TotalTokens: 1100},
+		model: "gpt-4o-2024-08-06",
+	}
+	memory := adapters.NewMemorySimpleAdapter(0)
+	a := agent.New().GrantMind(m).GrantMemory(memory).GrantAbility(newMockAbility())
+	a.SetPricing(usage.Pricing{
+		"gpt-4":  {Prompt: 30, Completion: 60},
+		"gpt-4o": {Prompt: 2.5, Completion: 10, Cached: 1.25},
+	})
+
+	output, err := a.Interact(&agent.InteractInput{SessionID: "s1", Messages: []message.Message{textMessage(message.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 每次调用：600*2.5 + 400*1.25 + 100*10 = 3000，共两次
+	wantCost := 2 * 3000 / 1e6
+	if u := output.Usage; u.TotalTokens != 2200 || math.Abs(u.Cost-wantCost) > 1e-12 {
+		t.Fatalf("unexpected turn usage %+v", u)
+	}
+	total, err := a.GetSessionUsage("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != output.Usage {
+		t.Fatalf("session usage %+v does not match turn usage %+v", total, output.Usage)
+	}
+}
@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"github.com/deep-project/agent/pkg/ability"
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+// turn 一轮交互的状态
+type turn struct {
+	input  *InteractInput
+	output *InteractOutput
+	items  []ability.Item    // 当前步骤使用的能力列表快照
+	emit   func(StreamEvent) // 流式交互时输出事件，为空则不输出
+
+	responseRetries int               // 回复不符合格式后重新要求回复的次数
+	retryMessages   []message.Message // 不符合格式的回复和纠正提示，只作为上下文，不存入记忆
+	handoff         string            // 转交对话的目标agent名称，不为空则结束本轮交互
+
+	prompt string               // 当前阶段的提示，作为system消息附加在上下文末尾，不存入记忆
+	format *mind.ResponseFormat // 当前阶段要求的回复格式，为空则使用input的格式
+}
+
+func newTurn(input *InteractInput) *turn {
+	return &turn{
+		input:  input,
+		output: &InteractOutput{SessionID: input.SessionID},
+	}
+}
+
+// responseFormat 当前要求的回复格式
+func (t *turn) responseFormat() *mind.ResponseFormat {
+	if t.format != nil {
+		return t.format
+	}
+	return t.input.ResponseFormat
+}
+
+func (t *turn) send(event StreamEvent) {
+	if t.emit == nil {
+		return
+	}
+	event.SessionID = t.input.SessionID
+	t.emit(event)
+}
+
+// emitDelta 将思维输出的增量内容转换为流式事件
+func (t *turn) emitDelta(delta *mind.StreamDelta) error {
+	switch delta.Type {
+	case mind.StreamDeltaText:
+		t.send(StreamEvent{Type: StreamEventTextDelta, Text: delta.Text})
+	case mind.StreamDeltaToolCallStart:
+		t.send(StreamEvent{Type: StreamEventToolCallStart, Index: delta.ToolCallIndex, ToolCall: &message.ToolCall{ID: delta.ToolCallID, ToolID: delta.ToolID}})
+	case mind.StreamDeltaToolCallArguments:
+		t.send(StreamEvent{Type: StreamEventToolCallArguments, Index: delta.ToolCallIndex, ToolCall: &message.ToolCall{ID: delta.ToolCallID, ToolID: delta.ToolID}, Arguments: delta.Arguments})
+	}
+	return nil
+}
+
+// addMessage 记录本轮交互产生的消息
+func (t *turn) addMessage(msg message.Message) {
+	t.output.Messages = append(t.output.Messages, msg)
+}
+
+// addToolResult 记录工具调用结果
+func (t *turn) addToolResult(msg message.Message) {
+	t.addMessage(msg)
+	t.send(StreamEvent{Type: StreamEventToolResult, Message: &msg})
+}
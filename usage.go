@@ -0,0 +1,34 @@
+package agent
+
+import (
+	"github.com/deep-project/agent/pkg/budget"
+	"github.com/deep-project/agent/pkg/mind"
+	"github.com/deep-project/agent/pkg/usage"
+)
+
+// SetPricing 设置模型价格表，用于计算费用
+func (a *Agent) SetPricing(pricing usage.Pricing) *Agent {
+	a.pricing = pricing
+	return a
+}
+
+// GetSessionUsage 获取会话的累计用量
+func (a *Agent) GetSessionUsage(sessionID string) (usage.Usage, error) {
+	return a.memory.GetUsage(sessionID)
+}
+
+// recordUsage 计算思维调用的费用，累加到本轮交互的用量并存入记忆
+func (a *Agent) recordUsage(t *turn, resp *mind.CallResponse) error {
+	u := resp.Usage
+	if u.IsZero() {
+		return nil
+	}
+	if u.Cost == 0 && a.pricing != nil {
+		u.Cost = a.pricing.Cost(resp.Model, u)
+	}
+	t.output.Usage.Add(u)
+	if err := a.addBudget(t.input.SessionID, budget.Spend{Tokens: u.TotalTokens, Cost: u.Cost}); err != nil {
+		return err
+	}
+	return a.memory.AddUsage(t.input.SessionID, u)
+}
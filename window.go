@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"github.com/deep-project/agent/pkg/message"
+	"github.com/deep-project/agent/pkg/mind"
+)
+
+// contextBudget 本轮交互可以用于输入的token数，为0表示不限制
+func (a *Agent) contextBudget(t *turn) int {
+	if t.input.MaxContextTokens > 0 {
+		return t.input.MaxContextTokens
+	}
+	return a.mind.ContextBudget()
+}
+
+// fitContextWindow 按照token预算从最早的消息开始裁切
+// system消息始终保留且不参与裁切，assistant的工具调用与对应的tool结果作为整体保留或删除
+// 最后一组消息即使超出预算也会保留
+func (a *Agent) fitContextWindow(t *turn, messages []message.Message, tools []mind.Tool) []message.Message {
+	budget := a.contextBudget(t)
+	if budget <= 0 {
+		return messages
+	}
+	tokenizer := a.mind.Tokenizer()
+	budget -= tokenizer.CountToolTokens(tools)
+
+	var groups [][]int // 对话消息分组，元素为消息的位置
+	keep := make([]bool, len(messages))
+	for i, msg := range messages {
+		switch {
+		case msg.Role == message.RoleSystem || msg.Role == message.RoleDeveloper:
+			keep[i] = true
+			budget -= tokenizer.CountMessageTokens(&msg)
+		case msg.Role == message.RoleTool && len(groups) > 0 && isToolCallGroup(messages, groups[len(groups)-1]):
+			groups[len(groups)-1] = append(groups[len(groups)-1], i)
+		default:
+			groups = append(groups, []int{i})
+		}
+	}
+
+	for g := len(groups) - 1; g >= 0; g-- {
+		var n int
+		for _, i := range groups[g] {
+			n += tokenizer.CountMessageTokens(&messages[i])
+		}
+		if n > budget && g < len(groups)-1 {
+			break
+		}
+		budget -= n
+		for _, i := range groups[g] {
+			keep[i] = true
+		}
+	}
+
+	res := make([]message.Message, 0, len(messages))
+	for i, msg := range messages {
+		if keep[i] {
+			res = append(res, msg)
+		}
+	}
+	return res
+}
+
+// isToolCallGroup 分组是否以带有工具调用的assistant消息开头
+func isToolCallGroup(messages []message.Message, group []int) bool {
+	first := messages[group[0]]
+	return first.Role == message.RoleAssistant && len(first.ToolCalls) > 0
+}